@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/protocol"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"golang.org/x/crypto/bcrypt"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Account logs in with name and password, an unknown name is registered on its first login.
+type Account struct{}
+
+// 防止同名账号并发注册
+var register sync.Mutex
+
+func (Account) Auth(packet *protocol.Packet) (*model.AuthInfo, error) {
+	req, err := parse(packet)
+	if err != nil {
+		return nil, err
+	}
+	if req.Name == "" || utf8.RuneCountInString(req.Name) > 20 || len(req.Password) < 6 || len(req.Password) > 72 {
+		return nil, consts.ErrorsAuthFail
+	}
+	account, err := database.GetStore().GetAccount(req.Name)
+	if err != nil {
+		return nil, err
+	}
+	if account == nil {
+		if account, err = registerAccount(req.Name, req.Password); err != nil {
+			return nil, err
+		}
+	} else if bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(req.Password)) != nil {
+		return nil, consts.ErrorsAuthFail
+	}
+	return &model.AuthInfo{ID: account.ID, Name: account.Name}, nil
+}
+
+// registerAccount registers the name, it hashes the password before taking the lock,
+// so a burst of registrations never holds up each other behind bcrypt.
+func registerAccount(name, password string) (*database.Account, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	account, created, err := saveAccount(name, string(hash))
+	if err != nil || created {
+		return account, err
+	}
+	// 同名账号刚被别人注册，按登录校验密码
+	if bcrypt.CompareHashAndPassword([]byte(account.Password), []byte(password)) != nil {
+		return nil, consts.ErrorsAuthFail
+	}
+	return account, nil
+}
+
+// saveAccount saves the account unless the name was taken in the meantime, it reports whether it was saved.
+func saveAccount(name, hash string) (*database.Account, bool, error) {
+	register.Lock()
+	defer register.Unlock()
+	account, err := database.GetStore().GetAccount(name)
+	if err != nil || account != nil {
+		return account, false, err
+	}
+	account = &database.Account{Name: name, Password: hash, CreateTime: time.Now()}
+	return account, true, database.GetStore().SaveAccount(account)
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/ratel-online/core/protocol"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+)
+
+func TestAccount(t *testing.T) {
+	if err := database.OpenStore(""); err != nil {
+		t.Fatal(err)
+	}
+	login := func(name, password string) (int64, error) {
+		info, err := Account{}.Auth(&protocol.Packet{Body: []byte(fmt.Sprintf(`{"name":%q,"password":%q}`, name, password))})
+		if err != nil {
+			return 0, err
+		}
+		return info.ID, nil
+	}
+	// 同名账号并发注册，只有一个密码生效
+	ids := make([]int64, 2)
+	errs := make([]error, 2)
+	wg := sync.WaitGroup{}
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ids[i], errs[i] = login("nico", fmt.Sprintf("secret%d", i))
+		}(i)
+	}
+	wg.Wait()
+	var id int64
+	for i := range ids {
+		if errs[i] != nil {
+			continue
+		}
+		if id != 0 && ids[i] != id {
+			t.Fatalf("expected one account, got %v", ids)
+		}
+		id = ids[i]
+	}
+	if id == 0 {
+		t.Fatalf("expected a registration to win, got %v", errs)
+	}
+	ok, failed := 0, 0
+	for i := 0; i < 2; i++ {
+		if got, err := login("nico", fmt.Sprintf("secret%d", i)); err == nil && got == id {
+			ok++
+		} else if err == consts.ErrorsAuthFail {
+			failed++
+		}
+	}
+	if ok != 1 || failed != 1 {
+		t.Fatalf("expected exactly one password to log in, got %d ok and %d failed", ok, failed)
+	}
+}
@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/protocol"
+	"github.com/ratel-online/server/consts"
+)
+
+const (
+	ModeTrust   = "trust"
+	ModeToken   = "token"
+	ModeAccount = "account"
+	ModeGuest   = "guest"
+)
+
+// Authenticator verifies the login packet of a new connection.
+type Authenticator interface {
+	Auth(packet *protocol.Packet) (*model.AuthInfo, error)
+}
+
+// Request is the login packet sent by clients, each authenticator reads its own fields.
+type Request struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Score    int64  `json:"score"`
+	Token    string `json:"token"`
+	Password string `json:"password"`
+	Guest    bool   `json:"guest"`
+	Protocol string `json:"protocol"` // text 或 json，为空时使用文本协议
+}
+
+// New returns the authenticator of the mode, the secret is only used to verify tokens.
+// The guest mode accepts guests only, use NewGuest to accept guests along with another mode.
+func New(mode, secret string) (Authenticator, error) {
+	switch mode {
+	case ModeTrust:
+		return Trust{}, nil
+	case ModeToken:
+		if secret == "" {
+			return nil, consts.ErrorsAuthSecretMissing
+		}
+		return NewToken(secret), nil
+	case ModeAccount:
+		return Account{}, nil
+	case ModeGuest:
+		return NewGuest(nil), nil
+	}
+	return nil, consts.ErrorsAuthModeInvalid
+}
+
+func parse(packet *protocol.Packet) (*Request, error) {
+	req := &Request{}
+	if err := packet.Unmarshal(req); err != nil {
+		return nil, consts.ErrorsAuthFail
+	}
+	return req, nil
+}
+
+// Protocol returns the protocol the client asked for in the login packet.
+func Protocol(packet *protocol.Packet) (int, error) {
+	req, err := parse(packet)
+	if err != nil {
+		return 0, err
+	}
+	mode, ok := consts.Protocols[req.Protocol]
+	if !ok {
+		return 0, consts.ErrorsProtocolInvalid
+	}
+	return mode, nil
+}
+
+// Trust accepts whatever the client claims, it is only meant for development.
+type Trust struct{}
+
+func (Trust) Auth(packet *protocol.Packet) (*model.AuthInfo, error) {
+	req, err := parse(packet)
+	if err != nil {
+		return nil, err
+	}
+	if req.ID <= 0 || req.ID >= consts.GuestIDOffset {
+		return nil, consts.ErrorsAuthFail
+	}
+	return &model.AuthInfo{ID: req.ID, Name: req.Name, Score: req.Score}, nil
+}
@@ -0,0 +1,25 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/ratel-online/core/protocol"
+	"github.com/ratel-online/server/consts"
+)
+
+func TestProtocol(t *testing.T) {
+	cases := map[string]int{
+		`{"id":1}`:                   consts.ProtocolText,
+		`{"id":1,"protocol":"text"}`: consts.ProtocolText,
+		`{"id":1,"protocol":"json"}`: consts.ProtocolJSON,
+	}
+	for body, expected := range cases {
+		mode, err := Protocol(&protocol.Packet{Body: []byte(body)})
+		if err != nil || mode != expected {
+			t.Fatalf("unexpected protocol of %s: %d %v", body, mode, err)
+		}
+	}
+	if _, err := Protocol(&protocol.Packet{Body: []byte(`{"protocol":"xml"}`)}); err != consts.ErrorsProtocolInvalid {
+		t.Fatalf("expected protocol invalid, got %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/protocol"
+	"github.com/ratel-online/core/util/strings"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	adjectives = []string{"Happy", "Lucky", "Brave", "Quiet", "Swift", "Clever", "Sunny", "Witty", "Gentle", "Bold"}
+	animals    = []string{"Panda", "Tiger", "Rabbit", "Fox", "Otter", "Koala", "Eagle", "Dolphin", "Crane", "Lynx"}
+)
+
+// 游客id从启动时的毫秒时间戳开始递增，重启后也不会和之前的游客重复
+var guestIds = time.Now().UnixNano() / int64(time.Millisecond)
+
+// Guest assigns a server side identity to clients logging in with guest set,
+// other logins are handed over to the next authenticator.
+type Guest struct {
+	next Authenticator
+	mu   sync.Mutex
+	rand *rand.Rand
+}
+
+func NewGuest(next Authenticator) *Guest {
+	return &Guest{next: next, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (g *Guest) Auth(packet *protocol.Packet) (*model.AuthInfo, error) {
+	req, err := parse(packet)
+	if err != nil {
+		return nil, err
+	}
+	if !req.Guest {
+		if g.next == nil {
+			return nil, consts.ErrorsAuthFail
+		}
+		info, err := g.next.Auth(packet)
+		if err == nil && info.ID >= consts.GuestIDOffset {
+			return nil, consts.ErrorsAuthFail
+		}
+		return info, err
+	}
+	name, err := g.name()
+	if err != nil {
+		return nil, err
+	}
+	return &model.AuthInfo{ID: consts.GuestIDOffset + atomic.AddInt64(&guestIds, 1), Name: name}, nil
+}
+
+// name generates a name which survives the desensitization and is not used by any online player,
+// the name is reserved right away so concurrent guests never get the same one.
+func (g *Guest) name() (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for i := 0; i < 64; i++ {
+		name := fmt.Sprintf("%s%s%02d", adjectives[g.rand.Intn(len(adjectives))], animals[g.rand.Intn(len(animals))], g.rand.Intn(100))
+		if strings.Desensitize(name) == name && database.ReserveName(name) {
+			return name, nil
+		}
+	}
+	return "", consts.ErrorsAuthFail
+}
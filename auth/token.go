@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/protocol"
+	"github.com/ratel-online/server/consts"
+	"strings"
+	"time"
+)
+
+// header of HS256 signed JWT, the only algorithm accepted.
+var header = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// Claims is the payload of the token issued to players.
+type Claims struct {
+	ID     int64  `json:"id"`
+	Name   string `json:"name"`
+	Expire int64  `json:"exp"`
+}
+
+// Token verifies JWT signed with HMAC-SHA256 by the server secret.
+type Token struct {
+	secret []byte
+}
+
+func NewToken(secret string) Token {
+	return Token{secret: []byte(secret)}
+}
+
+func (t Token) Auth(packet *protocol.Packet) (*model.AuthInfo, error) {
+	req, err := parse(packet)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := t.Verify(req.Token)
+	if err != nil {
+		return nil, err
+	}
+	return &model.AuthInfo{ID: claims.ID, Name: claims.Name}, nil
+}
+
+// Sign issues a token valid for ttl.
+func (t Token) Sign(id int64, name string, ttl time.Duration) (string, error) {
+	payload, err := json.Marshal(Claims{ID: id, Name: name, Expire: time.Now().Add(ttl).Unix()})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + t.sign(unsigned), nil
+}
+
+// Verify checks the signature and the expiration of the token.
+func (t Token) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != header {
+		return nil, consts.ErrorsAuthFail
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(t.sign(parts[0]+"."+parts[1]))) {
+		return nil, consts.ErrorsAuthFail
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, consts.ErrorsAuthFail
+	}
+	claims := &Claims{}
+	if err = json.Unmarshal(payload, claims); err != nil {
+		return nil, consts.ErrorsAuthFail
+	}
+	if claims.ID <= 0 || claims.Expire < time.Now().Unix() {
+		return nil, consts.ErrorsAuthFail
+	}
+	return claims, nil
+}
+
+func (t Token) sign(unsigned string) string {
+	mac := hmac.New(sha256.New, t.secret)
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ratel-online/core/protocol"
+)
+
+func TestToken(t *testing.T) {
+	token := NewToken("secret")
+	signed, err := token.Sign(1, "nico", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := token.Auth(&protocol.Packet{Body: []byte(`{"token":"` + signed + `"}`)})
+	if err != nil || info.ID != 1 || info.Name != "nico" {
+		t.Fatalf("unexpected auth info %v %v", info, err)
+	}
+	if _, err = NewToken("other").Verify(signed); err == nil {
+		t.Fatal("token signed by another secret should be rejected")
+	}
+	parts := strings.Split(signed, ".")
+	forged, _ := token.Sign(2, "nico", time.Minute)
+	if _, err = token.Verify(parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2]); err == nil {
+		t.Fatal("token with modified claims should be rejected")
+	}
+	expired, _ := token.Sign(1, "nico", -time.Minute)
+	if _, err = token.Verify(expired); err == nil {
+		t.Fatal("expired token should be rejected")
+	}
+}
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ratel-online/server/consts"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Config holds the tunables of the server, every field falls back to its default when left out.
+// A reload only affects what starts afterwards: new turns, new rooms and new games.
+type Config struct {
+	RobTimeout     time.Duration `yaml:"rob_timeout"`     // 抢地主超时
+	PlayTimeout    time.Duration `yaml:"play_timeout"`    // 出牌超时
+	MinPlayers     int           `yaml:"min_players"`     // 开局的最少人数
+	MaxPlayers     int           `yaml:"max_players"`     // 房间的最多人数
+	MatchPlayers   int           `yaml:"match_players"`   // 快速匹配的默认人数
+	RoomExpiry     time.Duration `yaml:"room_expiry"`     // 房间无活动多久后被清理
+	SweepInterval  time.Duration `yaml:"sweep_interval"`  // 清理房间的间隔
+	AuthTimeout    time.Duration `yaml:"auth_timeout"`    // 等待登录包的时间
+	PasswordLength int           `yaml:"password_length"` // 房间密码的最大长度
+
+	// GameTypes overrides the values above by game type, keyed by the name of the type, e.g. classic.
+	GameTypes map[string]Override `yaml:"game_types"`
+
+	overrides map[int]Override
+}
+
+// Override holds the values a game type may change, zero values keep the global ones.
+type Override struct {
+	RobTimeout  time.Duration `yaml:"rob_timeout"`
+	PlayTimeout time.Duration `yaml:"play_timeout"`
+	MinPlayers  int           `yaml:"min_players"`
+	MaxPlayers  int           `yaml:"max_players"`
+}
+
+// typeDefaults are the overrides built in the game types, e.g. run fast deals its 48 pokers to 3 or 4 players.
+var typeDefaults = map[int]Override{
+	consts.GameTypeRunFast: {MinPlayers: 3, MaxPlayers: 4},
+}
+
+// Default returns the values the server used to hardcode.
+func Default() *Config {
+	return &Config{
+		RobTimeout:     consts.RobTimeout,
+		PlayTimeout:    consts.PlayTimeout,
+		MinPlayers:     2,
+		MaxPlayers:     consts.MaxPlayers,
+		MatchPlayers:   consts.MinPlayers,
+		RoomExpiry:     24 * time.Hour,
+		SweepInterval:  time.Minute,
+		AuthTimeout:    3 * time.Second,
+		PasswordLength: 10,
+		overrides:      defaultOverrides(),
+	}
+}
+
+func defaultOverrides() map[int]Override {
+	overrides := map[int]Override{}
+	for gameType, o := range typeDefaults {
+		overrides[gameType] = o
+	}
+	return overrides
+}
+
+var current atomic.Value
+
+func init() {
+	current.Store(Default())
+}
+
+// Get returns the config in use, it must not be modified.
+func Get() *Config {
+	return current.Load().(*Config)
+}
+
+// Load reads the YAML file at path and puts it in use, the config in use is kept if the file is invalid.
+func Load(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	current.Store(c)
+	return nil
+}
+
+// Parse reads the config from YAML, missing fields get their defaults.
+func Parse(data []byte) (*Config, error) {
+	c := Default()
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	// 空文件全部使用默认值
+	if err := decoder.Decode(c); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) validate() error {
+	if c.RobTimeout < time.Second || c.PlayTimeout < time.Second {
+		return fmt.Errorf("rob_timeout and play_timeout must be at least 1s")
+	}
+	if err := validatePlayers(c.MinPlayers, c.MaxPlayers); err != nil {
+		return err
+	}
+	if c.MatchPlayers < c.MinPlayers || c.MatchPlayers > c.MaxPlayers {
+		return fmt.Errorf("match_players must be between min_players and max_players")
+	}
+	if c.RoomExpiry < time.Minute || c.SweepInterval < time.Second {
+		return fmt.Errorf("room_expiry must be at least 1m and sweep_interval at least 1s")
+	}
+	if c.AuthTimeout < time.Second {
+		return fmt.Errorf("auth_timeout must be at least 1s")
+	}
+	if c.PasswordLength < 1 || c.PasswordLength > 64 {
+		return fmt.Errorf("password_length must be between 1 and 64")
+	}
+	c.overrides = defaultOverrides()
+	for name, o := range c.GameTypes {
+		gameType := 0
+		for id, typeName := range consts.GameTypes {
+			if strings.EqualFold(name, typeName) {
+				gameType = id
+			}
+		}
+		if gameType == 0 {
+			return fmt.Errorf("unknown game type %s", name)
+		}
+		if (o.RobTimeout != 0 && o.RobTimeout < time.Second) || (o.PlayTimeout != 0 && o.PlayTimeout < time.Second) {
+			return fmt.Errorf("%s: rob_timeout and play_timeout must be at least 1s", name)
+		}
+		o = merge(o, typeDefaults[gameType])
+		if o.MinPlayers != 0 || o.MaxPlayers != 0 {
+			min, max := pick(o.MinPlayers, c.MinPlayers), pick(o.MaxPlayers, c.MaxPlayers)
+			if err := validatePlayers(min, max); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+		c.overrides[gameType] = o
+	}
+	return nil
+}
+
+func validatePlayers(min, max int) error {
+	if min < 2 || max < min {
+		return fmt.Errorf("players must satisfy 2 <= min_players <= max_players")
+	}
+	return nil
+}
+
+func (c *Config) RobTimeoutOf(gameType int) time.Duration {
+	return time.Duration(pick(int(c.overrides[gameType].RobTimeout), int(c.RobTimeout)))
+}
+
+func (c *Config) PlayTimeoutOf(gameType int) time.Duration {
+	return time.Duration(pick(int(c.overrides[gameType].PlayTimeout), int(c.PlayTimeout)))
+}
+
+func (c *Config) MinPlayersOf(gameType int) int {
+	return pick(c.overrides[gameType].MinPlayers, c.MinPlayers)
+}
+
+func (c *Config) MaxPlayersOf(gameType int) int {
+	return pick(c.overrides[gameType].MaxPlayers, c.MaxPlayers)
+}
+
+// MatchPlayersOf keeps the default of quick match within the players the game type allows.
+func (c *Config) MatchPlayersOf(gameType int) int {
+	n := c.MatchPlayers
+	if min := c.MinPlayersOf(gameType); n < min {
+		n = min
+	}
+	if max := c.MaxPlayersOf(gameType); n > max {
+		n = max
+	}
+	return n
+}
+
+// merge fills the values left out of the override with the built-in ones.
+func merge(o, defaults Override) Override {
+	o.RobTimeout = time.Duration(pick(int(o.RobTimeout), int(defaults.RobTimeout)))
+	o.PlayTimeout = time.Duration(pick(int(o.PlayTimeout), int(defaults.PlayTimeout)))
+	o.MinPlayers = pick(o.MinPlayers, defaults.MinPlayers)
+	o.MaxPlayers = pick(o.MaxPlayers, defaults.MaxPlayers)
+	return o
+}
+
+func pick(override, value int) int {
+	if override != 0 {
+		return override
+	}
+	return value
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"github.com/ratel-online/server/consts"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	c, err := Parse(nil)
+	if err != nil || c.RobTimeout != consts.RobTimeout || c.MaxPlayersOf(consts.GameTypeSkill) != consts.MaxPlayers || c.PasswordLength != 10 {
+		t.Fatalf("unexpected defaults %+v %v", c, err)
+	}
+
+	c, err = Parse([]byte(`
+play_timeout: 30s
+room_expiry: 2h
+game_types:
+  skill:
+    play_timeout: 1m
+    max_players: 4
+  runfast:
+    play_timeout: 20s
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.PlayTimeoutOf(consts.GameTypeClassic) != 30*time.Second || c.PlayTimeoutOf(consts.GameTypeSkill) != time.Minute {
+		t.Fatalf("unexpected play timeouts %v %v", c.PlayTimeoutOf(consts.GameTypeClassic), c.PlayTimeoutOf(consts.GameTypeSkill))
+	}
+	if c.RobTimeoutOf(consts.GameTypeSkill) != consts.RobTimeout || c.RoomExpiry != 2*time.Hour {
+		t.Fatalf("unexpected values %+v", c)
+	}
+	if c.MaxPlayersOf(consts.GameTypeSkill) != 4 || c.MaxPlayersOf(consts.GameTypeClassic) != consts.MaxPlayers || c.MatchPlayersOf(consts.GameTypeSkill) != 3 {
+		t.Fatalf("unexpected players %d %d", c.MaxPlayersOf(consts.GameTypeSkill), c.MaxPlayersOf(consts.GameTypeClassic))
+	}
+	// 跑得快的人数限制保留，除非配置中覆盖
+	if c.PlayTimeoutOf(consts.GameTypeRunFast) != 20*time.Second || c.MinPlayersOf(consts.GameTypeRunFast) != 3 || c.MaxPlayersOf(consts.GameTypeRunFast) != 4 {
+		t.Fatalf("unexpected run fast %v %d %d", c.PlayTimeoutOf(consts.GameTypeRunFast), c.MinPlayersOf(consts.GameTypeRunFast), c.MaxPlayersOf(consts.GameTypeRunFast))
+	}
+
+	for _, invalid := range []string{
+		"rob_timeout: 0s",
+		"min_players: 1",
+		"max_players: 2\nmatch_players: 3",
+		"password_length: 0",
+		"unknown: 1",
+		"game_types:\n  poker:\n    max_players: 4",
+		"game_types:\n  classic:\n    max_players: 1",
+		"game_types:\n  runfast:\n    min_players: 5",
+	} {
+		if _, err = Parse([]byte(invalid)); err == nil {
+			t.Fatalf("expected %q to be invalid", invalid)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	defer current.Store(Get())
+	path := filepath.Join(t.TempDir(), "ratel.yaml")
+	if err := os.WriteFile(path, []byte("auth_timeout: 5s\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(path); err != nil || Get().AuthTimeout != 5*time.Second {
+		t.Fatalf("unexpected config %+v %v", Get(), err)
+	}
+	// 配置有误时保留当前的配置
+	if err := os.WriteFile(path, []byte("auth_timeout: 0s\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Load(path); err == nil || Get().AuthTimeout != 5*time.Second {
+		t.Fatalf("expected the invalid config to be rejected, %+v %v", Get(), err)
+	}
+}
@@ -16,6 +16,42 @@ const (
 	StateSetting
 	StateWaiting
 	StateGame
+	StateMatch
+	StateReplay
+	StateWatch
+	StateRunFast
+)
+
+// 连接使用的协议，登录时协商，默认为文本
+const (
+	ProtocolText = 0
+	ProtocolJSON = 1
+)
+
+var Protocols = map[string]int{
+	"":     ProtocolText,
+	"text": ProtocolText,
+	"json": ProtocolJSON,
+}
+
+// 服务端扩展的消息码，从2000开始避免和core中的冲突
+const (
+	CodeMessage = 2000 + iota // 普通文本消息
+	CodeError
+	CodeHand
+	CodeTurn
+	CodePlay
+	CodePass
+	CodeRob
+	CodeSettle
+	CodeChat
+	CodeDeal
+	CodeRobPrompt
+	CodeLandlord
+	CodeSkill
+	CodeGameOver
+	CodeBid
+	CodeDouble
 )
 
 type SkillID int
@@ -47,9 +83,32 @@ const (
 	GameTypeClassic = 1
 	GameTypeLaiZi   = 2
 	GameTypeSkill   = 3
+	GameTypeRunFast = 4
+
+	BaseScore = 1
+	MaxBid    = 3 // 叫分模式最高叫3分
+
+	// 游客id的起始值，注册用户的id不会达到这个范围，同时保证在js中也能精确表示
+	GuestIDOffset = int64(1) << 52
 
 	RobTimeout  = 20 * time.Second
 	PlayTimeout = 40 * time.Second
+
+	AutopilotTimeouts = 2
+	AutopilotDelay    = 1 * time.Second
+
+	RobotLevelEasy = 1
+	RobotLevelHard = 2
+
+	DefaultRating       = 1500
+	MatchWindow         = 100
+	MatchWindowStep     = 50
+	MatchWindowInterval = 10 * time.Second
+
+	TopPlayers = 10
+
+	ReplayMatches = 10
+	ReplaySpeed   = 2 * time.Second
 )
 
 // Room properties.
@@ -59,6 +118,9 @@ const (
 	RoomPropsSkill      = "sk"
 	RoomPropsPassword   = "pwd"
 	RoomPropsPlayerNum  = "pn"
+	RoomPropsRobot      = "robot"
+	RoomPropsBid        = "bid"
+	RoomPropsDouble     = "double"
 )
 
 var RoomPropsKeys map[string]string = map[string]string{
@@ -67,6 +129,9 @@ var RoomPropsKeys map[string]string = map[string]string{
 	RoomPropsDotShuffle: "不洗牌模式",
 	RoomPropsPassword:   "房间密码",
 	RoomPropsPlayerNum:  "房间人数",
+	RoomPropsRobot:      "机器人数",
+	RoomPropsBid:        "叫分模式",
+	RoomPropsDouble:     "加倍模式",
 }
 
 var MnemonicSorted = []int{15, 14, 2, 1, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3}
@@ -91,23 +156,38 @@ var (
 	ErrorsTimeout                = NewErr(1, false, "Timeout. ")
 	ErrorsInputInvalid           = NewErr(1, false, "Input invalid. ")
 	ErrorsAuthFail               = NewErr(1, true, "Auth fail. ")
+	ErrorsAuthDuplicate          = NewErr(1, true, "Auth fail, already logged in elsewhere. ")
+	ErrorsAuthModeInvalid        = NewErr(1, true, "Auth mode invalid. ")
+	ErrorsAuthSecretMissing      = NewErr(1, true, "Auth secret missing. ")
+	ErrorsAdminTokenMissing      = NewErr(1, true, "Admin token missing. ")
+	ErrorsProtocolInvalid        = NewErr(1, true, "Protocol invalid. ")
 	ErrorsRoomInvalid            = NewErr(1, true, "Room invalid. ")
 	ErrorsGameTypeInvalid        = NewErr(1, false, "Game type invalid. ")
+	ErrorsPlayerNotExists        = NewErr(1, false, "Player not exists. ")
+	ErrorsMatchNotExists         = NewErr(1, false, "Match not exists. ")
 	ErrorsRoomPlayersIsFull      = NewErr(1, false, "Room players is fill. ")
 	ErrorsRoomPassword           = NewErr(1, false, "Room password error. ")
 	ErrorsJoinFailForRoomRunning = NewErr(1, false, "Join fail, room is running. ")
+	ErrorsWatchFailForRoomIdle   = NewErr(1, false, "Watch fail, room is not running. ")
 	ErrorsGamePlayersInvalid     = NewErr(1, false, "Game players invalid. ")
+	ErrorsServerShuttingDown     = NewErr(1, false, "Server is shutting down, no new games. ")
+	ErrorsMatchFailed            = NewErr(1, false, "Match failed, please try again. ")
 	ErrorsPokersFacesInvalid     = NewErr(1, false, "Pokers faces invalid. ")
 	ErrorsHaveToPlay             = NewErr(1, false, "Have to play. ")
+	ErrorsHaveToBeat             = NewErr(1, false, "Have to beat, you can't pass while you have pokers to beat. ")
 
 	GameTypes = map[int]string{
 		GameTypeClassic: "Classic",
 		GameTypeLaiZi:   "LaiZi",
 		GameTypeSkill:   "Skill",
-		//GameTypeRunFast: "RunFast",
+		GameTypeRunFast: "RunFast",
+	}
+	GameTypesIds = []int{GameTypeClassic, GameTypeLaiZi, GameTypeSkill, GameTypeRunFast}
+	RobotLevels  = map[string]int{
+		"easy": RobotLevelEasy,
+		"hard": RobotLevelHard,
 	}
-	GameTypesIds = []int{GameTypeClassic, GameTypeLaiZi, GameTypeSkill} // GameTypeLaiZi, GameTypeRunFast
-	RoomStates   = map[int]string{
+	RoomStates = map[int]string{
 		RoomStateWaiting: "Waiting",
 		RoomStateRunning: "Running",
 	}
@@ -0,0 +1,106 @@
+package database
+
+import (
+	"fmt"
+	"github.com/awesome-cap/hashmap"
+	"github.com/ratel-online/server/consts"
+	"sort"
+	"sync"
+)
+
+var (
+	gameTypesLock sync.RWMutex
+	gameTypes     = consts.GameTypesIds
+)
+
+// GameTypes returns the game types offered to players when they create or match a room.
+func GameTypes() []int {
+	gameTypesLock.RLock()
+	defer gameTypesLock.RUnlock()
+	return append([]int{}, gameTypes...)
+}
+
+// IsGameTypeOffered reports whether players may choose the game type.
+func IsGameTypeOffered(gameType int) bool {
+	for _, id := range GameTypes() {
+		if id == gameType {
+			return true
+		}
+	}
+	return false
+}
+
+// SetGameTypes changes the offered game types at runtime, rooms already created keep their type.
+func SetGameTypes(ids []int) error {
+	if len(ids) == 0 {
+		return consts.ErrorsGameTypeInvalid
+	}
+	offered := make([]int, 0, len(ids))
+	seen := map[int]bool{}
+	for _, id := range ids {
+		if _, ok := consts.GameTypes[id]; !ok {
+			return consts.ErrorsGameTypeInvalid
+		}
+		if !seen[id] {
+			seen[id] = true
+			offered = append(offered, id)
+		}
+	}
+	gameTypesLock.Lock()
+	defer gameTypesLock.Unlock()
+	gameTypes = offered
+	return nil
+}
+
+// OnlinePlayers returns the players connected right now, robots excluded.
+func OnlinePlayers() []*Player {
+	list := make([]*Player, 0)
+	players.Foreach(func(e *hashmap.Entry) {
+		if player := e.Value().(*Player); player.Online() && !player.IsRobot() {
+			list = append(list, player)
+		}
+	})
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// Kick closes the connection of the player, a player seated in a running game
+// is handed over to the autopilot just like on any other disconnection.
+func Kick(playerId int64) error {
+	player := getPlayer(playerId)
+	if player == nil || player.IsRobot() || !player.Online() {
+		return consts.ErrorsPlayerNotExists
+	}
+	_ = player.WriteString("You have been kicked by the server\n")
+	return player.conn.Close()
+}
+
+// DissolveRoom removes the room, its players are sent back home and a running game is dropped
+// without settlement.
+func DissolveRoom(roomId int64) error {
+	room := getRoom(roomId)
+	if room == nil {
+		return consts.ErrorsRoomInvalid
+	}
+	room.Lock()
+	defer room.Unlock()
+	Broadcast(room.ID, "The room has been dissolved by the server\n")
+	for id := range getRoomPlayers(room.ID) {
+		if player := getPlayer(id); player != nil && !player.IsRobot() {
+			player.RoomID = 0
+		}
+	}
+	deleteRoom(room)
+	return nil
+}
+
+// Announce writes the message to every online player, it returns how many got it.
+func Announce(msg string) int {
+	list := OnlinePlayers()
+	for _, player := range list {
+		_ = player.WriteString(fmt.Sprintf(">> Announcement: %s\n", msg))
+	}
+	return len(list)
+}
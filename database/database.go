@@ -1,30 +1,39 @@
 package database
 
 import (
+	"fmt"
 	"github.com/awesome-cap/hashmap"
 	"github.com/ratel-online/core/log"
 	modelx "github.com/ratel-online/core/model"
 	"github.com/ratel-online/core/network"
+	"github.com/ratel-online/core/protocol"
 	"github.com/ratel-online/core/util/async"
 	"github.com/ratel-online/core/util/json"
 	"github.com/ratel-online/core/util/strings"
+	"github.com/ratel-online/server/config"
 	"github.com/ratel-online/server/consts"
 	"sort"
-	stringx "strings"
+	"sync"
 	"sync/atomic"
 	"time"
 )
 
 var roomIds int64 = 0
+var robotIds int64 = 0
 var players = hashmap.New() // 存储连接过服务器的全部用户
 var connPlayers = hashmap.New()
 var rooms = hashmap.New()
 var roomPlayers = hashmap.New()
+var roomSpectators = hashmap.New()
+var reservedNames = map[string]time.Time{} // 已分配给游客但还未连上的昵称
+var reservedLock sync.Mutex
+var loggingIn = map[int64]bool{} // 正在登录的玩家
+var loginLock sync.Mutex
 
 func init() {
 	async.Async(func() {
 		for {
-			time.Sleep(1 * time.Minute)
+			time.Sleep(config.Get().SweepInterval)
 			rooms.Foreach(func(e *hashmap.Entry) {
 				roomCancel(e.Value().(*Room))
 			})
@@ -32,16 +41,86 @@ func init() {
 	})
 }
 
-func Connected(conn *network.Conn, info *modelx.AuthInfo) *Player {
+// Login binds the connection to the player, back into its running seat if there is one. It fails with
+// ErrorsAuthDuplicate while the player is online or logging in elsewhere, the check and the login are
+// one step so two logins of the same account never both get in.
+func Login(conn *network.Conn, info *modelx.AuthInfo, mode int) (*Player, bool, error) {
+	loginLock.Lock()
+	if loggingIn[info.ID] || IsOnline(info.ID) {
+		loginLock.Unlock()
+		return nil, false, consts.ErrorsAuthDuplicate
+	}
+	loggingIn[info.ID] = true
+	loginLock.Unlock()
+	// 登录完成时玩家已经在线，之后的登录由 IsOnline 拒绝
+	defer func() {
+		loginLock.Lock()
+		delete(loggingIn, info.ID)
+		loginLock.Unlock()
+	}()
+	if player := reconnect(conn, info, mode); player != nil {
+		return player, true, nil
+	}
+	return connected(conn, info, mode), false, nil
+}
+
+func connected(conn *network.Conn, info *modelx.AuthInfo, mode int) *Player {
 	player := &Player{
-		ID:    info.ID,
-		IP:    conn.IP(),
-		Name:  strings.Desensitize(info.Name),
-		Score: info.Score,
+		ID:   info.ID,
+		IP:   conn.IP(),
+		Name: strings.Desensitize(info.Name),
+	}
+	// 分数以服务端存储为准，不再信任客户端；游客不落库
+	if !isGuest(info.ID) {
+		record, err := store.GetPlayer(info.ID)
+		if err != nil {
+			log.Error(err)
+		}
+		if record == nil {
+			record = &PlayerRecord{ID: info.ID, CreateTime: time.Now()}
+		}
+		record.Name = player.Name
+		record.LoginTime = time.Now()
+		if err = savePlayer(record); err != nil {
+			log.Error(err)
+		}
+		player.Score = record.Score
 	}
-	player.Conn(conn)                  // 初始化play对象
+	player.Conn(conn, mode)            // 初始化play对象
 	players.Set(info.ID, player)       // 写入用户池
 	connPlayers.Set(conn.ID(), player) // 写入连接用户池
+	releaseName(player.Name)
+	return player
+}
+
+// isGuest reports whether the id was assigned to a guest, guests only live in memory.
+func isGuest(playerId int64) bool {
+	return playerId >= consts.GuestIDOffset
+}
+
+// reconnect rebinds a new connection to a player who is still seated in a running room.
+// It returns nil when there is no seat to go back to.
+func reconnect(conn *network.Conn, info *modelx.AuthInfo, mode int) *Player {
+	player := getPlayer(info.ID)
+	if player == nil || player.Online() {
+		return nil
+	}
+	room := getRoom(player.RoomID)
+	if room == nil {
+		return nil
+	}
+	room.Lock()
+	defer room.Unlock()
+	if room.State != consts.RoomStateRunning || room.Game == nil {
+		return nil
+	}
+	if _, ok := getRoomPlayers(room.ID)[player.ID]; !ok {
+		return nil
+	}
+	player.IP = conn.IP()
+	player.Conn(conn, mode)
+	connPlayers.Set(conn.ID(), player)
+	Broadcast(room.ID, fmt.Sprintf("%s reconnected, autopilot hands back control!\n", player.Name), player.ID)
 	return player
 }
 
@@ -58,22 +137,127 @@ func CreateRoom(creator int64, password string, playerNum int) *Room {
 	}
 	rooms.Set(room.ID, room)
 	roomPlayers.Set(room.ID, map[int64]bool{})
+	roomSpectators.Set(room.ID, map[int64]bool{})
 	return room
 }
 
+// AddRobot seats a server controlled robot of the level in the room, robots use negative ids
+// so they never collide with real players.
+func AddRobot(roomId int64, level int) (*Player, error) {
+	room := getRoom(roomId)
+	if room == nil {
+		return nil, consts.ErrorsRoomInvalid
+	}
+	room.Lock()
+	defer room.Unlock()
+	if room.State == consts.RoomStateRunning {
+		return nil, consts.ErrorsJoinFailForRoomRunning
+	}
+	if room.Players >= room.MaxPlayer {
+		return nil, consts.ErrorsRoomPlayersIsFull
+	}
+	playersIds := getRoomPlayers(roomId)
+	if playersIds == nil {
+		return nil, consts.ErrorsRoomInvalid
+	}
+	id := atomic.AddInt64(&robotIds, -1)
+	robot := &Player{
+		ID:     id,
+		Name:   fmt.Sprintf("Robot%d", -id),
+		RoomID: roomId,
+		robot:  level,
+		data:   make(chan *protocol.Packet),
+	}
+	close(robot.data)
+	players.Set(id, robot)
+	playersIds[id] = true
+	room.Players++
+	room.Robots++
+	room.ActiveTime = time.Now()
+	return robot, nil
+}
+
+// RemoveRobot takes one robot out of the room.
+func RemoveRobot(roomId int64) *Player {
+	room := getRoom(roomId)
+	if room == nil {
+		return nil
+	}
+	room.Lock()
+	defer room.Unlock()
+	if room.State == consts.RoomStateRunning {
+		return nil
+	}
+	for id := range getRoomPlayers(roomId) {
+		if robot := getPlayer(id); robot != nil && robot.IsRobot() {
+			leaveRoom(room, robot)
+			return robot
+		}
+	}
+	return nil
+}
+
+// VoidRoom gives up the game of the room when it can't go on, the players are told why and sent back home.
+func VoidRoom(roomId int64, msg string) {
+	room := getRoom(roomId)
+	if room == nil {
+		return
+	}
+	room.Lock()
+	defer room.Unlock()
+	Broadcast(room.ID, msg)
+	for id := range getRoomPlayers(room.ID) {
+		if player := getPlayer(id); player != nil && !player.IsRobot() {
+			player.RoomID = 0
+		}
+	}
+	deleteRoom(room)
+}
+
+// GetRobots returns the robots seated in the room.
+func GetRobots(roomId int64) []*Player {
+	robots := make([]*Player, 0)
+	for id := range getRoomPlayers(roomId) {
+		if robot := getPlayer(id); robot != nil && robot.IsRobot() {
+			robots = append(robots, robot)
+		}
+	}
+	return robots
+}
+
+var roomDeleted []func(roomId int64, game *Game)
+
+// OnRoomDeleted registers fn to clean up after a deleted room, the game is the one dropped with it, if any.
+// It is called with the room locked and must not lock it again.
+func OnRoomDeleted(fn func(roomId int64, game *Game)) {
+	roomDeleted = append(roomDeleted, fn)
+}
+
 func deleteRoom(room *Room) {
 	if room != nil {
+		for id := range getRoomPlayers(room.ID) {
+			if robot := getPlayer(id); robot != nil && robot.IsRobot() {
+				players.Del(id)
+			}
+		}
+		for id := range getRoomSpectators(room.ID) {
+			if spectator := getPlayer(id); spectator != nil {
+				spectator.WatchID = 0
+			}
+		}
 		rooms.Del(room.ID)
 		roomPlayers.Del(room.ID)
+		roomSpectators.Del(room.ID)
 		deleteGame(room.Game)
+		for _, fn := range roomDeleted {
+			fn(room.ID, room.Game)
+		}
 	}
 }
 
 func deleteGame(game *Game) {
 	if game != nil {
-		for _, state := range game.States {
-			close(state)
-		}
+		game.drop()
 	}
 }
 
@@ -113,6 +297,13 @@ func getRoomPlayers(roomId int64) map[int64]bool {
 	return nil
 }
 
+func getRoomSpectators(roomId int64) map[int64]bool {
+	if v, ok := roomSpectators.Get(roomId); ok {
+		return v.(map[int64]bool)
+	}
+	return nil
+}
+
 // 加入房间
 func JoinRoom(roomId, playerId int64, password string) error {
 
@@ -159,6 +350,55 @@ func JoinRoom(roomId, playerId int64, password string) error {
 	return nil
 }
 
+// WatchRoom attaches the player to the room as a spectator, spectators never take a seat.
+func WatchRoom(roomId, playerId int64, password string) error {
+	player := getPlayer(playerId)
+	if player == nil {
+		return consts.ErrorsExist
+	}
+	room := getRoom(roomId)
+	if room == nil {
+		return consts.ErrorsRoomInvalid
+	}
+	room.Lock()
+	defer room.Unlock()
+	if room.Password != password {
+		return consts.ErrorsRoomPassword
+	}
+	if room.State != consts.RoomStateRunning || room.Game == nil {
+		return consts.ErrorsWatchFailForRoomIdle
+	}
+	spectators := getRoomSpectators(roomId)
+	if spectators == nil {
+		return consts.ErrorsRoomInvalid
+	}
+	if !spectators[playerId] {
+		spectators[playerId] = true
+		room.Spectators++
+	}
+	player.WatchID = roomId
+	return nil
+}
+
+// UnwatchRoom detaches the spectator from the room.
+func UnwatchRoom(roomId, playerId int64) {
+	player := getPlayer(playerId)
+	if player != nil && player.WatchID == roomId {
+		player.WatchID = 0
+	}
+	room := getRoom(roomId)
+	if room == nil {
+		return
+	}
+	room.Lock()
+	defer room.Unlock()
+	spectators := getRoomSpectators(roomId)
+	if spectators[playerId] {
+		delete(spectators, playerId)
+		room.Spectators--
+	}
+}
+
 func LeaveRoom(roomId, playerId int64) {
 	room := getRoom(roomId)
 	if room != nil {
@@ -178,14 +418,21 @@ func leaveRoom(room *Room, player *Player) {
 		room.Players--
 		player.RoomID = 0
 		delete(playersIds, player.ID)
-		if len(playersIds) > 0 && room.Creator == player.ID {
+		if player.IsRobot() {
+			room.Robots--
+			players.Del(player.ID)
+		}
+		if len(playersIds) > room.Robots && room.Creator == player.ID {
 			for k := range playersIds {
-				room.Creator = k
-				break
+				if !getPlayer(k).IsRobot() {
+					room.Creator = k
+					break
+				}
 			}
 		}
 	}
-	if len(playersIds) == 0 {
+	// 只剩机器人的房间直接解散
+	if len(playersIds) <= room.Robots {
 		deleteRoom(room)
 	}
 	return
@@ -204,15 +451,15 @@ func offline(roomId, playerId int64) {
 }
 
 func roomCancel(room *Room) {
-	if room.ActiveTime.Add(24 * time.Hour).Before(time.Now()) {
-		log.Infof("room %d is timeout 24 hours, removed.\n", room.ID)
+	if expiry := config.Get().RoomExpiry; room.ActiveTime.Add(expiry).Before(time.Now()) {
+		log.Infof("room %d is timeout %s, removed.\n", room.ID, expiry)
 		deleteRoom(room)
 		return
 	}
 	living := false
 	playerIds := getRoomPlayers(room.ID)
 	for id := range playerIds {
-		if getPlayer(id).online {
+		if player := getPlayer(id); player != nil && player.Online() {
 			living = true
 			break
 		}
@@ -228,6 +475,20 @@ func RoomPlayers(roomId int64) map[int64]bool {
 }
 
 func Broadcast(roomId int64, msg string, exclude ...int64) {
+	ForeachListener(roomId, func(player *Player) {
+		_ = player.WriteString(">> " + msg)
+	}, exclude...)
+}
+
+func BroadcastObject(roomId int64, object interface{}, exclude ...int64) {
+	msg := json.Marshal(object)
+	ForeachListener(roomId, func(player *Player) {
+		_ = player.WriteString(string(msg))
+	}, exclude...)
+}
+
+// ForeachListener calls fn for the players and then the spectators of the room, everyone who gets its broadcasts.
+func ForeachListener(roomId int64, fn func(player *Player), exclude ...int64) {
 	room := getRoom(roomId)
 	if room == nil {
 		return
@@ -237,37 +498,114 @@ func Broadcast(roomId int64, msg string, exclude ...int64) {
 	for _, exc := range exclude {
 		excludeSet[exc] = true
 	}
-	roomPlayers := getRoomPlayers(roomId)
-	for playerId := range roomPlayers {
+	for playerId := range getRoomPlayers(roomId) {
 		if player := getPlayer(playerId); player != nil && !excludeSet[playerId] {
-			_ = player.WriteString(">> " + msg)
+			fn(player)
+		}
+	}
+	for playerId := range getRoomSpectators(roomId) {
+		if player := getPlayer(playerId); player != nil && !excludeSet[playerId] {
+			fn(player)
 		}
 	}
 }
 
-func BroadcastChat(player *Player, msg string, exclude ...int64) {
-	log.Infof("chat msg, player %s[%d] %s say: %s\n", player.Name, player.ID, player.IP, stringx.TrimSpace(msg))
-	Broadcast(player.RoomID, strings.Desensitize(msg), exclude...)
-}
-
-func BroadcastObject(roomId int64, object interface{}, exclude ...int64) {
-	room := getRoom(roomId)
-	if room == nil {
-		return
-	}
+// ForeachSpectator calls fn for the spectators of the room.
+func ForeachSpectator(roomId int64, fn func(player *Player), exclude ...int64) {
 	excludeSet := map[int64]bool{}
 	for _, exc := range exclude {
 		excludeSet[exc] = true
 	}
-	msg := json.Marshal(object)
-	playerIds := getRoomPlayers(roomId)
-	for playerId := range playerIds {
+	for playerId := range getRoomSpectators(roomId) {
 		if player := getPlayer(playerId); player != nil && !excludeSet[playerId] {
-			_ = player.WriteString(string(msg))
+			fn(player)
 		}
 	}
 }
 
+// SavePlayer persists the score of the player, robots and guests are never saved.
+func SavePlayer(player *Player) error {
+	if player.IsRobot() || isGuest(player.ID) {
+		return nil
+	}
+	record, err := store.GetPlayer(player.ID)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		record = &PlayerRecord{ID: player.ID, CreateTime: time.Now()}
+	}
+	record.Name = player.Name
+	record.Score = player.Score
+	return savePlayer(record)
+}
+
+// GetRating returns the rating of the player in the game type, new players start from the default rating.
+func GetRating(playerId int64, gameType int) int {
+	record, err := store.GetPlayer(playerId)
+	if err != nil || record == nil {
+		return consts.DefaultRating
+	}
+	if rating, ok := record.Ratings[gameType]; ok {
+		return rating
+	}
+	return consts.DefaultRating
+}
+
+// SetRating persists the rating of the player in the game type, robots and guests are never saved.
+func SetRating(playerId int64, gameType, rating int) error {
+	if playerId < 0 || isGuest(playerId) {
+		return nil
+	}
+	record, err := store.GetPlayer(playerId)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		record = &PlayerRecord{ID: playerId, CreateTime: time.Now()}
+	}
+	ratings := map[int]int{}
+	for k, v := range record.Ratings {
+		ratings[k] = v
+	}
+	ratings[gameType] = rating
+	record.Ratings = ratings
+	return savePlayer(record)
+}
+
+// IsOnline reports whether the player is connected right now.
+func IsOnline(playerId int64) bool {
+	player := getPlayer(playerId)
+	return player != nil && player.Online()
+}
+
+// ReserveName reserves the name for a guest who is about to connect, it reports false if the name is online
+// or reserved already. The name is released once the guest is connected, or expires after the auth timeout.
+func ReserveName(name string) bool {
+	reservedLock.Lock()
+	defer reservedLock.Unlock()
+	if at, ok := reservedNames[name]; ok && time.Since(at) < config.Get().AuthTimeout {
+		return false
+	}
+	online := false
+	players.Foreach(func(e *hashmap.Entry) {
+		if player := e.Value().(*Player); player.Online() && player.Name == name {
+			online = true
+		}
+	})
+	if online {
+		return false
+	}
+	reservedNames[name] = time.Now()
+	return true
+}
+
+func releaseName(name string) {
+	reservedLock.Lock()
+	defer reservedLock.Unlock()
+	delete(reservedNames, name)
+}
+
 func GetPlayer(playerId int64) *Player {
 	return getPlayer(playerId)
 }
@@ -0,0 +1,169 @@
+package database
+
+import (
+	"github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/network"
+	"github.com/ratel-online/core/protocol"
+	"github.com/ratel-online/server/consts"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGuest(t *testing.T) {
+	defer SetStore(GetStore())
+	SetStore(NewMemoryStore())
+	guest := &Player{ID: consts.GuestIDOffset + 1, Name: "HappyPanda01", Score: 10}
+	if err := SavePlayer(guest); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetRating(guest.ID, consts.GameTypeClassic, 1600); err != nil {
+		t.Fatal(err)
+	}
+	if records, _ := store.GetPlayers(); len(records) != 0 {
+		t.Fatalf("expected guests not to be saved, got %v", records)
+	}
+
+	if !ReserveName(guest.Name) || ReserveName(guest.Name) {
+		t.Fatal("expected the name to be reserved once")
+	}
+	guest.online = 1
+	players.Set(guest.ID, guest)
+	defer players.Del(guest.ID)
+	releaseName(guest.Name)
+	if ReserveName(guest.Name) {
+		t.Fatal("expected the name of an online guest to be taken")
+	}
+}
+
+// pipe is a connection fed by the test, it records what the server writes.
+type pipe struct {
+	packets chan *protocol.Packet
+	written int32
+}
+
+func newPipe() *pipe {
+	return &pipe{packets: make(chan *protocol.Packet, 1)}
+}
+
+func (p *pipe) Read() (*protocol.Packet, error) {
+	packet, ok := <-p.packets
+	if !ok {
+		return nil, consts.ErrorsChanClosed
+	}
+	return packet, nil
+}
+func (p *pipe) Write(protocol.Packet) error { atomic.AddInt32(&p.written, 1); return nil }
+func (p *pipe) Close() error                { return nil }
+func (p *pipe) IP() string                  { return "127.0.0.1" }
+
+func TestReconnect(t *testing.T) {
+	defer SetStore(GetStore())
+	SetStore(NewMemoryStore())
+	first, second := newPipe(), newPipe()
+	player := connected(network.Wrapper(first), &model.AuthInfo{ID: 21, Name: "nico"}, consts.ProtocolText)
+	// 另一个玩家在线，房间不会因断线而解散
+	other := connected(network.Wrapper(newPipe()), &model.AuthInfo{ID: 22, Name: "nico"}, consts.ProtocolText)
+	room := CreateRoom(player.ID, "", 3)
+	for _, id := range []int64{player.ID, other.ID} {
+		if err := JoinRoom(room.ID, id, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	room.Game = &Game{}
+	room.State = consts.RoomStateRunning
+	defer other.Offline()
+	defer player.Offline()
+
+	// 状态机持续写入的同时断线重连
+	stop, done := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				_ = player.Write([]byte("x"))
+			}
+		}
+	}()
+	player.Offline()
+	reconnected, ok, err := Login(network.Wrapper(second), &model.AuthInfo{ID: 21, Name: "nico"}, consts.ProtocolText)
+	close(stop)
+	<-done
+	if err != nil || !ok || reconnected != player {
+		t.Fatal("expected the player to be back in its seat")
+	}
+	go player.Listening()
+	player.StartTransaction()
+	second.packets <- &protocol.Packet{Body: []byte("y")}
+	if ans, err := player.AskForStringWithoutTransaction(time.Second); err != nil || ans != "y" {
+		t.Fatalf("expected to read from the new connection, got %q %v", ans, err)
+	}
+	player.StopTransaction()
+	if atomic.LoadInt32(&second.written) == 0 {
+		t.Fatal("expected the writes to go to the new connection")
+	}
+}
+
+func TestLogin(t *testing.T) {
+	defer SetStore(GetStore())
+	SetStore(NewMemoryStore())
+	var logged, duplicate int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := Login(network.Wrapper(newPipe()), &model.AuthInfo{ID: 31, Name: "nico"}, consts.ProtocolText)
+			if err == nil {
+				atomic.AddInt32(&logged, 1)
+			} else if err == consts.ErrorsAuthDuplicate {
+				atomic.AddInt32(&duplicate, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if logged != 1 || duplicate != 7 {
+		t.Fatalf("expected one login and 7 duplicates, got %d and %d", logged, duplicate)
+	}
+	getPlayer(31).Offline()
+	player, reconnected, err := Login(network.Wrapper(newPipe()), &model.AuthInfo{ID: 31, Name: "nico"}, consts.ProtocolText)
+	if err != nil || reconnected {
+		t.Fatalf("expected a fresh login once offline, got %v %v", reconnected, err)
+	}
+	player.Offline()
+}
+
+func TestVoidRoom(t *testing.T) {
+	room := CreateRoom(1, "", 3)
+	robot, err := AddRobot(room.ID, consts.RobotLevelEasy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	game := &Game{States: map[int64]chan int{robot.ID: make(chan int, 1)}}
+	room.Game = game
+	room.State = consts.RoomStateRunning
+	VoidRoom(room.ID, "void\n")
+	if GetRoom(room.ID) != nil || GetPlayer(robot.ID) != nil || game.Wait(robot.ID) != 0 {
+		t.Fatal("expected the room and its game to be dropped")
+	}
+}
+
+func TestWatchRoom(t *testing.T) {
+	room := CreateRoom(1, "", 3)
+	spectator := &Player{ID: 41, Name: "nico"}
+	players.Set(spectator.ID, spectator)
+	defer players.Del(spectator.ID)
+	defer deleteRoom(room)
+	if err := WatchRoom(room.ID, spectator.ID, ""); err != consts.ErrorsWatchFailForRoomIdle {
+		t.Fatalf("expected a waiting room not to be watched, got %v", err)
+	}
+	room.Game = &Game{}
+	room.State = consts.RoomStateRunning
+	if err := WatchRoom(room.ID, spectator.ID, ""); err != nil || spectator.WatchID != room.ID || room.Spectators != 1 {
+		t.Fatalf("expected to watch the running room, got %v", err)
+	}
+}
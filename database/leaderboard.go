@@ -0,0 +1,141 @@
+package database
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// index keeps the rated players of every game type sorted, best first, and the players by their lowercase names.
+// It is loaded from the store on the first query and kept up to date as the records are saved, so queries never
+// scan the store again.
+var index = struct {
+	sync.Mutex
+	loaded bool
+	types  map[int][]*PlayerRecord
+	names  map[string][]*PlayerRecord
+	ids    map[int64]string // 玩家当前的索引昵称
+}{}
+
+// loadIndex reads every player once, the index has to be locked.
+func loadIndex() error {
+	if index.loaded {
+		return nil
+	}
+	records, err := store.GetPlayers()
+	if err != nil {
+		return err
+	}
+	index.types = map[int][]*PlayerRecord{}
+	index.names = map[string][]*PlayerRecord{}
+	index.ids = map[int64]string{}
+	for _, record := range records {
+		for t := range record.Ratings {
+			index.types[t] = append(index.types[t], record)
+		}
+		key := strings.ToLower(record.Name)
+		index.names[key] = append(index.names[key], record)
+		index.ids[record.ID] = key
+	}
+	for t, rated := range index.types {
+		t, rated := t, rated
+		sort.Slice(rated, func(i, j int) bool {
+			return better(rated[i], rated[j], t)
+		})
+	}
+	index.loaded = true
+	return nil
+}
+
+// Leaderboard returns the players rated in the game type, best first.
+func Leaderboard(gameType int) ([]*PlayerRecord, error) {
+	index.Lock()
+	defer index.Unlock()
+	if err := loadIndex(); err != nil {
+		return nil, err
+	}
+	return append([]*PlayerRecord{}, index.types[gameType]...), nil
+}
+
+// playerNamed returns the player of the name, case insensitive, the latest to log in if the name is shared.
+func playerNamed(name string) (*PlayerRecord, error) {
+	index.Lock()
+	defer index.Unlock()
+	if err := loadIndex(); err != nil {
+		return nil, err
+	}
+	var found *PlayerRecord
+	for _, record := range index.names[strings.ToLower(name)] {
+		if found == nil || record.LoginTime.After(found.LoginTime) {
+			found = record
+		}
+	}
+	return found, nil
+}
+
+// savePlayer saves the record and moves it to its place on the leaderboards and under its name.
+func savePlayer(record *PlayerRecord) error {
+	if err := store.SavePlayer(record); err != nil {
+		return err
+	}
+	index.Lock()
+	defer index.Unlock()
+	if !index.loaded {
+		return nil
+	}
+	// 存一份副本，调用方之后再改动记录也不影响索引
+	saved := *record
+	for t, rated := range index.types {
+		index.types[t] = without(rated, saved.ID)
+	}
+	for t := range saved.Ratings {
+		rated := index.types[t]
+		i := sort.Search(len(rated), func(i int) bool {
+			return better(&saved, rated[i], t)
+		})
+		rated = append(rated, nil)
+		copy(rated[i+1:], rated[i:])
+		rated[i] = &saved
+		index.types[t] = rated
+	}
+	// 玩家可能改过昵称，从原来的昵称下移除
+	if key, ok := index.ids[saved.ID]; ok {
+		if named := without(index.names[key], saved.ID); len(named) > 0 {
+			index.names[key] = named
+		} else {
+			delete(index.names, key)
+		}
+	}
+	key := strings.ToLower(saved.Name)
+	index.names[key] = append(index.names[key], &saved)
+	index.ids[saved.ID] = key
+	return nil
+}
+
+// without removes the record of the player from the list.
+func without(records []*PlayerRecord, playerId int64) []*PlayerRecord {
+	for i, r := range records {
+		if r.ID == playerId {
+			return append(records[:i], records[i+1:]...)
+		}
+	}
+	return records
+}
+
+// resetIndex drops the index, it is loaded again from the new store.
+func resetIndex() {
+	index.Lock()
+	defer index.Unlock()
+	index.loaded = false
+	index.types = nil
+	index.names = nil
+	index.ids = nil
+}
+
+// better reports whether a is ranked above b in the game type, ties go to the older id.
+func better(a, b *PlayerRecord, gameType int) bool {
+	if a.Ratings[gameType] != b.Ratings[gameType] {
+		return a.Ratings[gameType] > b.Ratings[gameType]
+	}
+	return a.ID < b.ID
+}
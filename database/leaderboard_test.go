@@ -0,0 +1,51 @@
+package database
+
+import (
+	"github.com/ratel-online/server/consts"
+	"testing"
+)
+
+// scanStore counts the full scans of the players.
+type scanStore struct {
+	Store
+	scans int
+}
+
+func (s *scanStore) GetPlayers() ([]*PlayerRecord, error) {
+	s.scans++
+	return s.Store.GetPlayers()
+}
+
+func TestLeaderboard(t *testing.T) {
+	defer SetStore(GetStore())
+	s := &scanStore{Store: NewMemoryStore()}
+	SetStore(s)
+	_ = SetRating(1, consts.GameTypeClassic, 1500)
+	_ = SetRating(2, consts.GameTypeClassic, 1600)
+	_ = SetRating(3, consts.GameTypeLaiZi, 1700)
+	ids := func(gameType int) []int64 {
+		records, err := Leaderboard(gameType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		list := make([]int64, 0)
+		for _, record := range records {
+			list = append(list, record.ID)
+		}
+		return list
+	}
+	if list := ids(consts.GameTypeClassic); len(list) != 2 || list[0] != 2 || list[1] != 1 {
+		t.Fatalf("unexpected leaderboard %v", list)
+	}
+	_ = SetRating(1, consts.GameTypeClassic, 1650)
+	_ = SetRating(3, consts.GameTypeClassic, 1600)
+	if list := ids(consts.GameTypeClassic); len(list) != 3 || list[0] != 1 || list[1] != 2 || list[2] != 3 {
+		t.Fatalf("unexpected leaderboard %v", list)
+	}
+	if list := ids(consts.GameTypeLaiZi); len(list) != 1 || list[0] != 3 {
+		t.Fatalf("unexpected leaderboard %v", list)
+	}
+	if s.scans != 1 {
+		t.Fatalf("expected the players to be scanned once, got %d", s.scans)
+	}
+}
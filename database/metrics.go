@@ -0,0 +1,28 @@
+package database
+
+import (
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/metrics"
+)
+
+func init() {
+	metrics.NewGaugeFunc("ratel_online_players", "Players connected right now.", func() []metrics.Sample {
+		return []metrics.Sample{{Value: float64(len(OnlinePlayers()))}}
+	})
+	metrics.NewGaugeFunc("ratel_rooms", "Rooms by state and game type.", func() []metrics.Sample {
+		counts := map[[2]int]int{}
+		for _, room := range GetRooms() {
+			counts[[2]int{room.State, room.Type}]++
+		}
+		samples := make([]metrics.Sample, 0)
+		for _, state := range []int{consts.RoomStateWaiting, consts.RoomStateRunning} {
+			for _, gameType := range consts.GameTypesIds {
+				samples = append(samples, metrics.Sample{
+					Labels: []string{consts.RoomStates[state], consts.GameTypes[gameType]},
+					Value:  float64(counts[[2]int{state, gameType}]),
+				})
+			}
+		}
+		return samples
+	}, "state", "type")
+}
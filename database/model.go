@@ -11,43 +11,64 @@ import (
 	"github.com/ratel-online/core/util/json"
 	"github.com/ratel-online/core/util/poker"
 	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/metrics"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type Player struct {
-	ID     int64  `json:"id"`
-	IP     string `json:"ip"`
-	Name   string `json:"name"`
-	Score  int64  `json:"score"`
-	Mode   int    `json:"mode"`
-	Type   int    `json:"type"`
-	RoomID int64  `json:"roomId"`
-
-	conn   *network.Conn
-	data   chan *protocol.Packet
-	read   bool
-	state  consts.StateID
-	online bool
+	ID      int64  `json:"id"`
+	IP      string `json:"ip"`
+	Name    string `json:"name"`
+	Score   int64  `json:"score"`
+	Type    int    `json:"type"`
+	RoomID  int64  `json:"roomId"`
+	WatchID int64  `json:"watchId"` // 正在观战的房间
+
+	lock     sync.RWMutex // 重连时换上新连接，旧的状态机可能仍在读写
+	conn     *network.Conn
+	data     chan *protocol.Packet
+	mode     int // 连接协商的协议，随连接一起替换
+	state    consts.StateID
+	read     int32 // 以下标记由连接协程和状态机并发读写，使用原子操作
+	online   int32
+	running  int32
+	auto     int32
+	timeouts int32
+	robot    int
 }
 
 func (p *Player) Write(bytes []byte) error {
-	return p.conn.Write(protocol.Packet{
+	if p.IsRobot() {
+		return nil
+	}
+	defer metrics.WriteLatency.Since(time.Now())
+	conn, _ := p.connection()
+	return conn.Write(protocol.Packet{
 		Body: bytes,
 	})
 }
 
 func (p *Player) Offline() {
-	p.online = false
-	_ = p.conn.Close()
-	close(p.data)
+	conn, data := p.connection()
+	_ = conn.Close()
+	close(data)
+	atomic.StoreInt32(&p.online, 0)
+	if p.WatchID != 0 {
+		UnwatchRoom(p.WatchID, p.ID)
+	}
 	room := getRoom(p.RoomID)
 	if room != nil {
 		room.Lock()
 		defer room.Unlock()
-		Broadcast(room.ID, fmt.Sprintf("%s lost connection!\n", p.Name))
+		if room.State == consts.RoomStateRunning {
+			Broadcast(room.ID, fmt.Sprintf("%s lost connection, autopilot takes over!\n", p.Name))
+		} else {
+			Broadcast(room.ID, fmt.Sprintf("%s lost connection!\n", p.Name))
+		}
 		if room.State == consts.RoomStateWaiting {
 			leaveRoom(room, p)
 		}
@@ -56,37 +77,64 @@ func (p *Player) Offline() {
 }
 
 func (p *Player) Listening() error {
+	conn, data := p.connection()
 	for {
-		pack, err := p.conn.Read()
+		pack, err := conn.Read()
 		if err != nil {
 			log.Error(err)
 			return err
 		}
-		if p.read {
-			p.data <- pack
+		if atomic.CompareAndSwapInt32(&p.auto, 1, 0) {
+			atomic.StoreInt32(&p.timeouts, 0)
+			Broadcast(p.RoomID, fmt.Sprintf("%s took back control from autopilot\n", p.Name))
+		}
+		if atomic.LoadInt32(&p.read) == 1 {
+			data <- pack
 		}
 	}
 }
 
 // 向客户端发生消息
 func (p *Player) WriteString(data string) error {
+	if p.IsRobot() {
+		return nil
+	}
+	defer metrics.WriteLatency.Since(time.Now())
 	time.Sleep(30 * time.Millisecond)
-	return p.conn.Write(protocol.Packet{
+	if p.Mode() == consts.ProtocolJSON {
+		return p.writeObject(model.Data{Code: consts.CodeMessage, Msg: data})
+	}
+	conn, _ := p.connection()
+	return conn.Write(protocol.Packet{
 		Body: []byte(data),
 	})
 }
 
 func (p *Player) WriteObject(data interface{}) error {
-	return p.conn.Write(protocol.Packet{
+	if p.IsRobot() {
+		return nil
+	}
+	defer metrics.WriteLatency.Since(time.Now())
+	return p.writeObject(data)
+}
+
+func (p *Player) writeObject(data interface{}) error {
+	conn, _ := p.connection()
+	return conn.Write(protocol.Packet{
 		Body: json.Marshal(data),
 	})
 }
 
 func (p *Player) WriteError(err error) error {
-	if err == consts.ErrorsExist {
+	if err == consts.ErrorsExist || p.IsRobot() {
 		return err
 	}
-	return p.conn.Write(protocol.Packet{
+	defer metrics.WriteLatency.Since(time.Now())
+	if p.Mode() == consts.ProtocolJSON {
+		return p.writeObject(model.Data{Code: consts.CodeError, Msg: err.Error()})
+	}
+	conn, _ := p.connection()
+	return conn.Write(protocol.Packet{
 		Body: []byte(err.Error() + "\n"),
 	})
 }
@@ -99,14 +147,15 @@ func (p *Player) AskForPacket(timeout ...time.Duration) (*protocol.Packet, error
 
 func (p *Player) askForPacket(timeout ...time.Duration) (*protocol.Packet, error) {
 	var packet *protocol.Packet
+	_, data := p.connection()
 	if len(timeout) > 0 {
 		select {
-		case packet = <-p.data:
+		case packet = <-data:
 		case <-time.After(timeout[0]):
 			return nil, consts.ErrorsTimeout
 		}
 	} else {
-		packet = <-p.data
+		packet = <-data
 	}
 	if packet == nil {
 		return nil, consts.ErrorsChanClosed
@@ -151,12 +200,12 @@ func (p *Player) AskForStringWithoutTransaction(timeout ...time.Duration) (strin
 }
 
 func (p *Player) StartTransaction() {
-	p.read = true
+	atomic.StoreInt32(&p.read, 1)
 	_ = p.WriteString(consts.IsStart)
 }
 
 func (p *Player) StopTransaction() {
-	p.read = false
+	atomic.StoreInt32(&p.read, 0)
 	_ = p.WriteString(consts.IsStop)
 }
 
@@ -168,13 +217,79 @@ func (p *Player) GetState() consts.StateID {
 	return p.state
 }
 
-func (p *Player) Conn(conn *network.Conn) {
+// Auto reports whether the autopilot plays for the player, either because it is offline
+// or because it timed out too many times in a row.
+func (p *Player) Auto() bool {
+	return atomic.LoadInt32(&p.auto) == 1 || !p.Online()
+}
+
+func (p *Player) SetAuto(auto bool) {
+	if auto {
+		atomic.StoreInt32(&p.auto, 1)
+	} else {
+		atomic.StoreInt32(&p.auto, 0)
+	}
+	atomic.StoreInt32(&p.timeouts, 0)
+}
+
+func (p *Player) IncrTimeouts() int {
+	return int(atomic.AddInt32(&p.timeouts, 1))
+}
+
+func (p *Player) ResetTimeouts() {
+	atomic.StoreInt32(&p.timeouts, 0)
+}
+
+// IsRobot reports whether the player is a server controlled robot without connection.
+func (p *Player) IsRobot() bool {
+	return p.robot > 0
+}
+
+func (p *Player) RobotLevel() int {
+	return p.robot
+}
+
+// Online reports whether the player is connected right now.
+func (p *Player) Online() bool {
+	return atomic.LoadInt32(&p.online) == 1
+}
+
+// StartRunning marks the state machine of the player alive, it reports false if one is running already,
+// so a reconnect never starts a second one.
+func (p *Player) StartRunning() bool {
+	return atomic.CompareAndSwapInt32(&p.running, 0, 1)
+}
+
+func (p *Player) StopRunning() {
+	atomic.StoreInt32(&p.running, 0)
+}
+
+// Conn binds the connection and its protocol to the player, a reconnect swaps them under the lock
+// while the state machine keeps running.
+func (p *Player) Conn(conn *network.Conn, mode int) {
+	p.lock.Lock()
 	p.conn = conn
+	p.mode = mode
 	p.data = make(chan *protocol.Packet, 8)
-	p.online = true
+	p.lock.Unlock()
+	atomic.StoreInt32(&p.online, 1)
+}
+
+// Mode returns the protocol negotiated by the connection.
+func (p *Player) Mode() int {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.mode
+}
+
+// connection returns the current connection and its input channel.
+func (p *Player) connection() (*network.Conn, chan *protocol.Packet) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.conn, p.data
 }
 
-func (p Player) Model() model.Player {
+func (p *Player) Model() model.Player {
 	modelPlayer := model.Player{
 		ID:    p.ID,
 		Name:  p.Name,
@@ -188,7 +303,7 @@ func (p Player) Model() model.Player {
 	return modelPlayer
 }
 
-func (p Player) String() string {
+func (p *Player) String() string {
 	return fmt.Sprintf("%s[%d]", p.Name, p.ID)
 }
 
@@ -201,21 +316,22 @@ type Room struct {
 	State      int              `json:"state"`   // 状态
 	Players    int              `json:"players"` // 玩家数
 	Robots     int              `json:"robots"`
-	Creator    int64            `json:"creator"` //创建者
+	Spectators int              `json:"spectators"` // 观战人数，不计入玩家数
+	Creator    int64            `json:"creator"`    //创建者
 	ActiveTime time.Time        `json:"activeTime"`
 	Properties *hashmap.HashMap `json:"properties"`
 	MaxPlayer  int              `json:"maxPlayer"` // 该房间允许的最大人数 0无限制
 	Password   string           `json:"password"`  // 房间密码 默认空 ， 最多10位
 }
 
-func (r Room) SetProperty(key string, v bool) {
+func (r *Room) SetProperty(key string, v bool) {
 	// 必须是合法的key才允许设置，不然客户端可以恶意提交，占满服务器内存
 	if _, ok := consts.RoomPropsKeys[key]; ok {
 		r.Properties.Set(key, v)
 	}
 }
 
-func (r Room) GetProperty(key string) bool {
+func (r *Room) GetProperty(key string) bool {
 	v, ok := r.Properties.Get(key)
 	if ok {
 		return v.(bool)
@@ -223,7 +339,7 @@ func (r Room) GetProperty(key string) bool {
 	return false
 }
 
-func (r Room) GetProperties() map[string]bool {
+func (r *Room) GetProperties() map[string]bool {
 	props := map[string]bool{}
 	r.Properties.Foreach(func(e *hashmap.Entry) {
 		props[e.Key().(string)] = e.Value().(bool)
@@ -231,7 +347,7 @@ func (r Room) GetProperties() map[string]bool {
 	return props
 }
 
-func (r Room) Model() model.Room {
+func (r *Room) Model() model.Room {
 	return model.Room{
 		ID:        r.ID,
 		Type:      r.Type,
@@ -244,6 +360,7 @@ func (r Room) Model() model.Room {
 }
 
 type Game struct {
+	Type        int                     `json:"type"` // 游戏类型
 	Players     []int64                 `json:"players"`
 	Groups      map[int64]int           `json:"groups"`
 	States      map[int64]chan int      `json:"states"`
@@ -251,13 +368,21 @@ type Game struct {
 	Universals  []int                   `json:"universals"`
 	Decks       int                     `json:"decks"`
 	Additional  model.Pokers            `json:"pocket"`
+	Base        int                     `json:"base"`
 	Multiple    int                     `json:"multiple"`
+	Bombs       int                     `json:"bombs"`
+	Rockets     int                     `json:"rockets"`
+	Plays       map[int64]int           `json:"plays"`
 	FirstPlayer int64                   `json:"firstPlayer"`
 	LastPlayer  int64                   `json:"lastPlayer"`
+	Turn        int64                   `json:"turn"`
 	Robs        []int64                 `json:"robs"`
 	FirstRob    int64                   `json:"firstRob"`
 	LastRob     int64                   `json:"lastRob"`
 	FinalRob    bool                    `json:"finalRob"`
+	Concessions int                     `json:"concessions"` // 二人斗地主中地主让给农民的牌数
+	Removed     model.Pokers            `json:"removed"`     // 二人斗地主中扣下不用的牌
+	OpenHand    bool                    `json:"openHand"`    // 地主是否明牌
 	LastFaces   *model.Faces            `json:"lastFaces"`
 	LastPokers  model.Pokers            `json:"lastPokers"`
 	Mnemonic    map[int]int             `json:"mnemonic"`
@@ -267,29 +392,196 @@ type Game struct {
 	PlayTimeOut map[int64]time.Duration `json:"playTimeOut"`
 	Rules       poker.Rules             `json:"rules"`
 	Discards    model.Pokers            `json:"discards"`
+	StartTime   time.Time               `json:"startTime"`
+	Events      []MatchEvent            `json:"events"`
+
+	lock     sync.Mutex
+	done     chan struct{} // 游戏被丢弃时关闭
+	snapshot *Snapshot
+}
+
+// Snapshot is a copy of the game as of its last event, it is safe to read while the game goes on.
+type Snapshot struct {
+	Hands      map[int64]model.Pokers `json:"hands"`
+	Groups     map[int64]int          `json:"groups"`
+	Pocket     model.Pokers           `json:"pocket"`
+	Universals []int                  `json:"universals"`
+	Base       int                    `json:"base"`
+	Multiple   int                    `json:"multiple"`
+	Turn       int64                  `json:"turn"`
+	LastPlayer int64                  `json:"lastPlayer"`
+	LastPokers model.Pokers           `json:"lastPokers"`
+	StartTime  time.Time              `json:"startTime"`
+}
+
+// Record appends the event to the log of the hand, it is saved with the match on settlement.
+func (g *Game) Record(event MatchEvent) {
+	event.Time = time.Now()
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.Events = append(g.Events, event)
 }
 
-func (g Game) NextPlayer(curr int64) int64 {
+// ClearEvents drops the log of a deal nobody robbed, the log of the match starts over with the redeal.
+func (g *Game) ClearEvents() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.Events = nil
+}
+
+// RecordHands attaches the hands changed by a skill to the skill event recorded last.
+func (g *Game) RecordHands(hands map[int64]model.Pokers) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if n := len(g.Events); n > 0 && g.Events[n-1].Type == MatchEventSkill {
+		g.Events[n-1].Hands = hands
+	}
+}
+
+// RecordedEvents copies the log of the hand, it may be read while the game goes on.
+func (g *Game) RecordedEvents() []MatchEvent {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return append([]MatchEvent{}, g.Events...)
+}
+
+// Notify hands the state to the player, it reports false once the game was dropped.
+func (g *Game) Notify(playerId int64, state int) bool {
+	done := g.Done()
+	select {
+	case <-done:
+		return false
+	default:
+	}
+	select {
+	case g.States[playerId] <- state:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// Wait returns the next state handed to the player, or 0 once the game was dropped.
+func (g *Game) Wait(playerId int64) int {
+	select {
+	case state := <-g.States[playerId]:
+		return state
+	case <-g.Done():
+		return 0
+	}
+}
+
+// Done returns the channel closed when the game is dropped.
+func (g *Game) Done() <-chan struct{} {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.done == nil {
+		g.done = make(chan struct{})
+	}
+	return g.done
+}
+
+// drop wakes up everyone waiting on the game, it may be called more than once.
+func (g *Game) drop() {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.done == nil {
+		g.done = make(chan struct{})
+	}
+	select {
+	case <-g.done:
+	default:
+		close(g.done)
+	}
+}
+
+// TakeSnapshot copies the game for readers of other goroutines, only the goroutine playing the game
+// may call it, nobody else changes the game meanwhile.
+func (g *Game) TakeSnapshot() {
+	groups := map[int64]int{}
+	for id, group := range g.Groups {
+		groups[id] = group
+	}
+	snapshot := &Snapshot{
+		Hands:      g.Hands(),
+		Groups:     groups,
+		Pocket:     append(model.Pokers{}, g.Additional...),
+		Universals: append([]int{}, g.Universals...),
+		Base:       g.Base,
+		Multiple:   g.Multiple,
+		Turn:       g.Turn,
+		LastPlayer: g.LastPlayer,
+		LastPokers: append(model.Pokers{}, g.LastPokers...),
+		StartTime:  g.StartTime,
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.snapshot = snapshot
+}
+
+// Snapshot returns the copy taken on the last event of the game, nil before the deal.
+func (g *Game) Snapshot() *Snapshot {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.snapshot
+}
+
+// Hands copies the pokers of every player, the pokers are changed in place while playing.
+func (g *Game) Hands() map[int64]model.Pokers {
+	hands := map[int64]model.Pokers{}
+	for id, pokers := range g.Pokers {
+		hands[id] = append(model.Pokers{}, pokers...)
+	}
+	return hands
+}
+
+func (g *Game) NextPlayer(curr int64) int64 {
 	idx := arrays.IndexOf(g.Players, curr)
 	return g.Players[(idx+1)%len(g.Players)]
 }
 
-func (g Game) PrevPlayer(curr int64) int64 {
+func (g *Game) PrevPlayer(curr int64) int64 {
 	idx := arrays.IndexOf(g.Players, curr)
 	return g.Players[(idx+len(g.Players))%len(g.Players)]
 }
 
-func (g Game) IsTeammate(player1, player2 int64) bool {
+func (g *Game) IsTeammate(player1, player2 int64) bool {
 	return g.Groups[player1] == g.Groups[player2]
 }
 
-func (g Game) IsLandlord(playerId int64) bool {
-	return g.Groups[playerId] == 1
+// IsDuel reports whether it is the two-player game, which is dealt and settled by its own rules.
+func (g *Game) IsDuel() bool {
+	return len(g.Players) == 2 && g.Rules != nil && g.Rules.Reserved()
+}
+
+// IsLandlord reports whether the player is the landlord, there is no landlord in run fast.
+func (g *Game) IsLandlord(playerId int64) bool {
+	return g.Type != consts.GameTypeRunFast && g.Groups[playerId] == 1
+}
+
+// maxRules is implemented by the rules which have a top hand of their own.
+type maxRules interface {
+	IsMax(faces model.Faces) bool
+}
+
+// IsMax reports whether nothing can be played over the faces.
+func (g *Game) IsMax(faces model.Faces) bool {
+	if rules, ok := g.Rules.(maxRules); ok {
+		return rules.IsMax(faces)
+	}
+	if g.Decks == 1 && len(faces.Keys) == 2 {
+		if (faces.Keys[0] == 14 && faces.Keys[1] == 15) || (faces.Keys[0] == 15 && faces.Keys[1] == 14) {
+			return true
+		}
+	}
+	return false
 }
 
-func (g Game) Team(playerId int64) string {
+func (g *Game) Team(playerId int64) string {
 	if g.Properties[consts.RoomPropsSkill] {
 		return "team" + strconv.Itoa(g.Groups[playerId])
+	} else if g.Type == consts.GameTypeRunFast {
+		return "player"
 	} else {
 		if !g.IsLandlord(playerId) {
 			return "peasant"
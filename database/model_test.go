@@ -0,0 +1,74 @@
+package database
+
+import (
+	"github.com/ratel-online/core/util/poker"
+	"github.com/ratel-online/server/rule"
+	"testing"
+)
+
+func TestIsMax(t *testing.T) {
+	parse := func(rules poker.Rules, keys ...int) []int64 {
+		scores := make([]int64, 0)
+		for _, faces := range poker.ParseFaces(poker.GetPokers(keys...), rules) {
+			scores = append(scores, faces.Score)
+		}
+		return scores
+	}
+	classic := Game{Decks: 1, Rules: rule.LandlordRules}
+	rocket := poker.ParseFaces(poker.GetPokers(14, 15), rule.LandlordRules)[0]
+	if !classic.IsMax(rocket) {
+		t.Fatal("expected the rocket to be max with one deck")
+	}
+
+	four := Game{Decks: 2, Rules: rule.FourPlayerRules}
+	jokers := poker.ParseFaces(poker.GetPokers(14, 14, 15, 15), rule.FourPlayerRules)
+	if len(jokers) != 1 || !four.IsMax(jokers[0]) || four.IsMax(rocket) {
+		t.Fatalf("expected only the four jokers to be max, got %v", jokers)
+	}
+	// 炸弹按张数比大小，张数相同再比点数
+	bombs := [][]int{{5, 5, 5, 5}, {2, 2, 2, 2}, {3, 3, 3, 3, 3}, {2, 2, 2, 2, 2, 2, 2}, {3, 3, 3, 3, 3, 3, 3, 3}}
+	last := int64(0)
+	for _, keys := range bombs {
+		scores := parse(rule.FourPlayerRules, keys...)
+		if len(scores) != 1 || scores[0] <= last {
+			t.Fatalf("expected %v to beat the smaller bombs, got %v", keys, scores)
+		}
+		last = scores[0]
+	}
+	if jokers[0].Score <= last {
+		t.Fatal("expected the four jokers to beat eight of a kind")
+	}
+}
+
+func TestStartRunning(t *testing.T) {
+	player := &Player{ID: 1}
+	if !player.StartRunning() {
+		t.Fatal("expected the first state machine to start")
+	}
+	if player.StartRunning() {
+		t.Fatal("expected a second state machine to be refused")
+	}
+	player.StopRunning()
+	if !player.StartRunning() {
+		t.Fatal("expected the state machine to start again once stopped")
+	}
+}
+
+func TestAuto(t *testing.T) {
+	player := &Player{ID: 1, online: 1}
+	if player.Auto() || player.IncrTimeouts() != 1 {
+		t.Fatal("expected an online player to play by itself")
+	}
+	player.SetAuto(true)
+	if !player.Auto() || player.IncrTimeouts() != 1 {
+		t.Fatal("expected the autopilot to take over with the timeouts reset")
+	}
+	player.SetAuto(false)
+	if player.Auto() {
+		t.Fatal("expected the player to take back control")
+	}
+	player.online = 0
+	if !player.Auto() {
+		t.Fatal("expected the autopilot to play for an offline player")
+	}
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"github.com/ratel-online/server/consts"
+	"strconv"
+	"strings"
+)
+
+// Profile sums up the match history of a player.
+type Profile struct {
+	Record        *PlayerRecord
+	Games         int
+	Wins          int
+	LandlordGames int
+	LandlordWins  int
+	PeasantGames  int
+	PeasantWins   int
+	MaxMultiple   int
+}
+
+// GetProfile looks the player up by id or name and sums up its matches.
+func GetProfile(nameOrId string) (*Profile, error) {
+	record, err := findPlayer(strings.TrimSpace(nameOrId))
+	if err != nil {
+		return nil, err
+	}
+	matches, err := store.GetMatches(record.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	profile := &Profile{Record: record}
+	for _, match := range matches {
+		if match.Void {
+			continue
+		}
+		for _, p := range match.Players {
+			if p.ID != record.ID {
+				continue
+			}
+			profile.Games++
+			if p.Winner {
+				profile.Wins++
+			}
+			// 技能模式和跑得快各自为战，不区分地主农民
+			if match.Type != consts.GameTypeSkill && match.Type != consts.GameTypeRunFast {
+				if p.Landlord {
+					profile.LandlordGames++
+					if p.Winner {
+						profile.LandlordWins++
+					}
+				} else {
+					profile.PeasantGames++
+					if p.Winner {
+						profile.PeasantWins++
+					}
+				}
+			}
+			if match.Multiple > profile.MaxMultiple {
+				profile.MaxMultiple = match.Multiple
+			}
+		}
+	}
+	return profile, nil
+}
+
+// findPlayer prefers the id, names are matched case insensitively and are not unique,
+// so the latest login wins.
+func findPlayer(nameOrId string) (*PlayerRecord, error) {
+	if id, err := strconv.ParseInt(nameOrId, 10, 64); err == nil {
+		record, err := store.GetPlayer(id)
+		if err != nil {
+			return nil, err
+		}
+		if record != nil {
+			return record, nil
+		}
+	}
+	found, err := playerNamed(nameOrId)
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, consts.ErrorsPlayerNotExists
+	}
+	return found, nil
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"github.com/ratel-online/server/consts"
+	"testing"
+	"time"
+)
+
+func TestGetProfile(t *testing.T) {
+	defer SetStore(GetStore())
+	SetStore(NewMemoryStore())
+	_ = store.SavePlayer(&PlayerRecord{ID: 1, Name: "nico"})
+	_ = store.SavePlayer(&PlayerRecord{ID: 2, Name: "ratel"})
+	matches := []*Match{
+		{Type: consts.GameTypeClassic, Multiple: 4, Players: []MatchPlayer{{ID: 1, Landlord: true, Winner: true}, {ID: 2}}},
+		{Type: consts.GameTypeClassic, Multiple: 8, Players: []MatchPlayer{{ID: 1, Landlord: true}, {ID: 2, Winner: true}}},
+		{Type: consts.GameTypeLaiZi, Multiple: 2, Players: []MatchPlayer{{ID: 1, Winner: true}, {ID: 2, Landlord: true}}},
+		{Type: consts.GameTypeSkill, Multiple: 16, Players: []MatchPlayer{{ID: 1, Winner: true}, {ID: 2}}},
+		{Type: consts.GameTypeRunFast, Multiple: 1, Players: []MatchPlayer{{ID: 1}, {ID: 2, Winner: true}}},
+	}
+	for _, match := range matches {
+		if err := store.SaveMatch(match); err != nil {
+			t.Fatal(err)
+		}
+	}
+	profile, err := GetProfile("Nico")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if profile.Record.ID != 1 || profile.Games != 5 || profile.Wins != 3 || profile.MaxMultiple != 16 {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+	if profile.LandlordGames != 2 || profile.LandlordWins != 1 || profile.PeasantGames != 1 || profile.PeasantWins != 1 {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+	if profile, err = GetProfile("2"); err != nil || profile.Record.Name != "ratel" {
+		t.Fatalf("unexpected profile %+v %v", profile, err)
+	}
+	if _, err = GetProfile("nobody"); err != consts.ErrorsPlayerNotExists {
+		t.Fatalf("expected player not exists, got %v", err)
+	}
+}
+
+func TestFindPlayer(t *testing.T) {
+	defer SetStore(GetStore())
+	s := &scanStore{Store: NewMemoryStore()}
+	SetStore(s)
+	now := time.Now()
+	_ = savePlayer(&PlayerRecord{ID: 1, Name: "nico", LoginTime: now.Add(-time.Hour)})
+	_ = savePlayer(&PlayerRecord{ID: 2, Name: "Nico", LoginTime: now})
+	name := func(name string) int64 {
+		record, err := findPlayer(name)
+		if err != nil {
+			return 0
+		}
+		return record.ID
+	}
+	if id := name("NICO"); id != 2 {
+		t.Fatalf("expected the latest login, got %d", id)
+	}
+	// 改名后原来的昵称找不到这个玩家
+	_ = savePlayer(&PlayerRecord{ID: 2, Name: "maki", LoginTime: now})
+	_ = savePlayer(&PlayerRecord{ID: 3, Name: "umi", LoginTime: now})
+	if name("nico") != 1 || name("maki") != 2 || name("umi") != 3 || name("nobody") != 0 {
+		t.Fatal("unexpected players found by name")
+	}
+	if s.scans != 1 {
+		t.Fatalf("expected the players to be scanned once, got %d", s.scans)
+	}
+}
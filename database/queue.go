@@ -0,0 +1,156 @@
+package database
+
+import (
+	"github.com/ratel-online/server/consts"
+	"sort"
+	"sync"
+	"time"
+)
+
+// queueKey separates the quick match queues by game type and players number.
+type queueKey struct {
+	gameType int
+	players  int
+}
+
+type queueEntry struct {
+	player   *Player
+	rating   int
+	joinTime time.Time
+}
+
+// window is the rating difference the entry accepts, it widens the longer the player waits.
+func (e queueEntry) window() int {
+	waited := int(time.Since(e.joinTime) / consts.MatchWindowInterval)
+	return consts.MatchWindow + waited*consts.MatchWindowStep
+}
+
+var queueLock sync.Mutex
+var queues = map[queueKey][]*queueEntry{}
+
+// Enqueue puts the player into the quick match queue of the game type and players number.
+func Enqueue(player *Player, gameType, players int) {
+	queueLock.Lock()
+	defer queueLock.Unlock()
+	key := queueKey{gameType: gameType, players: players}
+	queues[key] = append(queues[key], &queueEntry{
+		player:   player,
+		rating:   GetRating(player.ID, gameType),
+		joinTime: time.Now(),
+	})
+}
+
+// Dequeue takes the player out of the queues, it returns false if the player has already been matched.
+func Dequeue(playerId int64) bool {
+	queueLock.Lock()
+	defer queueLock.Unlock()
+	for key, entries := range queues {
+		for i, e := range entries {
+			if e.player.ID == playerId {
+				queues[key] = append(entries[:i], entries[i+1:]...)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Queued reports whether the player is waiting in any queue.
+func Queued(playerId int64) bool {
+	queueLock.Lock()
+	defer queueLock.Unlock()
+	for _, entries := range queues {
+		for _, e := range entries {
+			if e.player.ID == playerId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Matching groups queued players of similar ratings, every pair of a group is within the windows of both.
+// The oldest waiters are served first and every group is handed to matched while the queues are locked, so the players are either still
+// queued or already seated when they look at it. matched returns the players it could not seat,
+// they are dropped from the queue and the rest of the group stays queued.
+func Matching(matched func(gameType int, players []*Player) []*Player) {
+	queueLock.Lock()
+	defer queueLock.Unlock()
+	for key, entries := range queues {
+		if len(entries) < key.players {
+			continue
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].joinTime.Before(entries[j].joinTime)
+		})
+		used := map[*queueEntry]bool{}
+		for _, e := range entries {
+			if used[e] {
+				continue
+			}
+			others := make([]*queueEntry, 0)
+			for _, o := range entries {
+				if o != e && !used[o] {
+					others = append(others, o)
+				}
+			}
+			if len(others) < key.players-1 {
+				break
+			}
+			sort.SliceStable(others, func(i, j int) bool {
+				return abs(others[i].rating-e.rating) < abs(others[j].rating-e.rating)
+			})
+			group := []*queueEntry{e}
+			for _, o := range others {
+				if len(group) == key.players {
+					break
+				}
+				if acceptsAll(o, group) {
+					group = append(group, o)
+				}
+			}
+			if len(group) < key.players {
+				continue
+			}
+			players := make([]*Player, 0, len(group))
+			for _, g := range group {
+				players = append(players, g.player)
+			}
+			failed := map[*Player]bool{}
+			for _, player := range matched(key.gameType, players) {
+				failed[player] = true
+			}
+			for _, g := range group {
+				if len(failed) == 0 || failed[g.player] {
+					used[g] = true
+				}
+			}
+		}
+		rest := make([]*queueEntry, 0)
+		for _, e := range entries {
+			if !used[e] {
+				rest = append(rest, e)
+			}
+		}
+		queues[key] = rest
+	}
+}
+
+// acceptsAll reports whether the entry and every member of the group are within the windows of each other,
+// a player who has just joined is not matched far away only because somebody else has waited long.
+func acceptsAll(e *queueEntry, group []*queueEntry) bool {
+	for _, g := range group {
+		diff := abs(e.rating - g.rating)
+		if diff > e.window() || diff > g.window() {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
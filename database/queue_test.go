@@ -0,0 +1,63 @@
+package database
+
+import (
+	"github.com/ratel-online/server/consts"
+	"testing"
+	"time"
+)
+
+func TestMatchingFailed(t *testing.T) {
+	defer SetStore(GetStore())
+	SetStore(NewMemoryStore())
+	defer func() {
+		queues = map[queueKey][]*queueEntry{}
+	}()
+	group := []*Player{{ID: 1}, {ID: 2}, {ID: 3}}
+	for _, player := range group {
+		Enqueue(player, consts.GameTypeClassic, 3)
+	}
+	Matching(func(gameType int, players []*Player) []*Player {
+		return players[1:2]
+	})
+	if !Queued(1) || Queued(2) || !Queued(3) {
+		t.Fatal("expected only the player who failed to be dropped")
+	}
+	Enqueue(&Player{ID: 4}, consts.GameTypeClassic, 3)
+	matched := 0
+	Matching(func(gameType int, players []*Player) []*Player {
+		matched = len(players)
+		return nil
+	})
+	if matched != 3 || Queued(1) || Queued(3) || Queued(4) {
+		t.Fatalf("expected the rest to be matched again, got %d", matched)
+	}
+}
+
+func TestMatchingWindow(t *testing.T) {
+	defer SetStore(GetStore())
+	SetStore(NewMemoryStore())
+	defer func() {
+		queues = map[queueKey][]*queueEntry{}
+	}()
+	key := queueKey{gameType: consts.GameTypeClassic, players: 3}
+	// 1 等了很久，可以接受任何人，但 3 刚加入，和 2 差得太远
+	queues[key] = []*queueEntry{
+		{player: &Player{ID: 1}, rating: 1500, joinTime: time.Now().Add(-time.Hour)},
+		{player: &Player{ID: 2}, rating: 1500, joinTime: time.Now()},
+		{player: &Player{ID: 3}, rating: 1900, joinTime: time.Now()},
+	}
+	var matched []*Player
+	match := func(gameType int, players []*Player) []*Player {
+		matched = players
+		return nil
+	}
+	Matching(match)
+	if matched != nil {
+		t.Fatalf("expected no match, got %v", matched)
+	}
+	queues[key] = append(queues[key], &queueEntry{player: &Player{ID: 4}, rating: 1550, joinTime: time.Now()})
+	Matching(match)
+	if len(matched) != 3 || !Queued(3) || Queued(1) || Queued(2) || Queued(4) {
+		t.Fatalf("expected 1, 2 and 4 to be matched, got %v", matched)
+	}
+}
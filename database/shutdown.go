@@ -0,0 +1,89 @@
+package database
+
+import (
+	"fmt"
+	"github.com/ratel-online/core/log"
+	"github.com/ratel-online/server/consts"
+	"sync/atomic"
+	"time"
+)
+
+var shuttingDown int32
+
+// ShuttingDown reports whether the server is draining, no new games start meanwhile.
+func ShuttingDown() bool {
+	return atomic.LoadInt32(&shuttingDown) == 1
+}
+
+// Shutdown tells every player the server is going down and waits for the running games to finish,
+// the games still running after the deadline are aborted and recorded as void. All connections are
+// closed at last, the listeners have to be stopped before.
+func Shutdown(deadline time.Duration) {
+	atomic.StoreInt32(&shuttingDown, 1)
+	Announce(fmt.Sprintf("The server is shutting down, running games have %s to finish", deadline))
+	timeout := time.After(deadline)
+	for running := runningRooms(); len(running) > 0; running = runningRooms() {
+		select {
+		case <-timeout:
+			for _, room := range running {
+				abortGame(room)
+			}
+		case <-time.After(time.Second):
+		}
+	}
+	for _, player := range OnlinePlayers() {
+		_ = player.WriteString("The server is down, bye\n")
+		conn, _ := player.connection()
+		_ = conn.Close()
+	}
+}
+
+func runningRooms() []*Room {
+	list := make([]*Room, 0)
+	for _, room := range GetRooms() {
+		if room.State == consts.RoomStateRunning {
+			list = append(list, room)
+		}
+	}
+	return list
+}
+
+// abortGame records the game of the room as void and dissolves the room. The game may still be going on,
+// so the events and the table are copied rather than read in place.
+func abortGame(room *Room) {
+	room.Lock()
+	game, gameType := room.Game, room.Type
+	room.Unlock()
+	if game != nil {
+		match := &Match{
+			Version:    MatchVersion,
+			RoomID:     room.ID,
+			Type:       gameType,
+			Properties: game.Properties,
+			Events:     game.RecordedEvents(),
+			Void:       true,
+			StartTime:  game.StartTime,
+			EndTime:    time.Now(),
+		}
+		groups := map[int64]int{}
+		if snapshot := game.Snapshot(); snapshot != nil {
+			match.Multiple = snapshot.Multiple
+			groups = snapshot.Groups
+		}
+		for _, id := range game.Players {
+			landlord := !game.Properties[consts.RoomPropsSkill] && gameType != consts.GameTypeRunFast && groups[id] == 1
+			matchPlayer := MatchPlayer{ID: id, Landlord: landlord, Rating: GetRating(id, gameType)}
+			if player := getPlayer(id); player != nil {
+				matchPlayer.Name = player.Name
+			}
+			match.Players = append(match.Players, matchPlayer)
+		}
+		if err := store.SaveMatch(match); err != nil {
+			log.Error(err)
+		}
+		log.Infof("room %d aborted on shutdown, match %d recorded as void\n", room.ID, match.ID)
+	}
+	if err := DissolveRoom(room.ID); err != nil {
+		log.Error(err)
+	}
+}
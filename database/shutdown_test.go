@@ -0,0 +1,50 @@
+package database
+
+import (
+	"github.com/ratel-online/server/consts"
+	"sync/atomic"
+	"testing"
+)
+
+func TestShutdown(t *testing.T) {
+	defer SetStore(GetStore())
+	SetStore(NewMemoryStore())
+	defer atomic.StoreInt32(&shuttingDown, 0)
+
+	room := CreateRoom(1, "", 3)
+	robots := make([]int64, 0)
+	for i := 0; i < 3; i++ {
+		robot, err := AddRobot(room.ID, consts.RobotLevelEasy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		robots = append(robots, robot.ID)
+	}
+	states := map[int64]chan int{}
+	for _, id := range robots {
+		states[id] = make(chan int, 1)
+	}
+	game := &Game{Players: robots, States: states, Groups: map[int64]int{robots[0]: 1}, Multiple: 2, Properties: map[string]bool{}}
+	game.Record(MatchEvent{Type: MatchEventDeal})
+	game.TakeSnapshot()
+	room.Game = game
+	room.State = consts.RoomStateRunning
+
+	Shutdown(0)
+	if !ShuttingDown() {
+		t.Fatal("expected shutting down")
+	}
+	if GetRoom(room.ID) != nil {
+		t.Fatal("expected the running room to be aborted")
+	}
+	match, err := GetStore().GetMatch(1)
+	if err != nil || match == nil || !match.Void || match.RoomID != room.ID || len(match.Players) != 3 || match.Multiple != 2 || len(match.Events) != 1 {
+		t.Fatalf("unexpected void match %+v %v", match, err)
+	}
+	if !match.Players[0].Landlord || match.Players[0].Score != 0 {
+		t.Fatalf("unexpected players %+v", match.Players)
+	}
+	if game.Notify(robots[0], 1) || game.Wait(robots[1]) != 0 {
+		t.Fatal("expected the game to be dropped")
+	}
+}
@@ -0,0 +1,152 @@
+package database
+
+import (
+	"encoding/json"
+	"github.com/ratel-online/core/model"
+	"github.com/ratel-online/server/consts"
+	"io"
+	"time"
+)
+
+// Store persists what has to survive a restart: players, their scores and the match history.
+type Store interface {
+	// GetPlayer returns nil if the player has never been saved.
+	GetPlayer(id int64) (*PlayerRecord, error)
+	SavePlayer(record *PlayerRecord) error
+	// GetPlayers returns every saved player in no particular order.
+	GetPlayers() ([]*PlayerRecord, error)
+	// SaveMatch assigns an id to the match if it has none.
+	SaveMatch(match *Match) error
+	// GetMatch returns nil if the match does not exist.
+	GetMatch(id int64) (*Match, error)
+	// GetMatches returns the latest matches of the player, newest first.
+	GetMatches(playerId int64, limit int) ([]*Match, error)
+	// GetAccount returns nil if no account has the name.
+	GetAccount(name string) (*Account, error)
+	// SaveAccount assigns a new player id to the account if it has none.
+	SaveAccount(account *Account) error
+	Close() error
+}
+
+// Account is a local login, the password is stored as a bcrypt hash.
+type Account struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Password   string    `json:"password"`
+	CreateTime time.Time `json:"createTime"`
+}
+
+type PlayerRecord struct {
+	ID         int64       `json:"id"`
+	Name       string      `json:"name"`
+	Score      int64       `json:"score"`
+	Ratings    map[int]int `json:"ratings"` // 各游戏类型的等级分
+	CreateTime time.Time   `json:"createTime"`
+	LoginTime  time.Time   `json:"loginTime"`
+}
+
+// MatchVersion is the version of the match format, it changes only when a field changes its meaning.
+const MatchVersion = 1
+
+type Match struct {
+	ID         int64           `json:"id"`
+	Version    int             `json:"version"`
+	RoomID     int64           `json:"roomId"`
+	Type       int             `json:"type"`
+	Multiple   int             `json:"multiple"`
+	Players    []MatchPlayer   `json:"players"`
+	Properties map[string]bool `json:"properties,omitempty"`
+	Events     []MatchEvent    `json:"events,omitempty"` // 完整的对局过程，可以用来回放
+	Void       bool            `json:"void,omitempty"`   // 停服时被中止的对局，不计分也不计入战绩
+	StartTime  time.Time       `json:"startTime"`
+	EndTime    time.Time       `json:"endTime"`
+}
+
+type MatchPlayer struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	Landlord bool   `json:"landlord"`
+	Winner   bool   `json:"winner"`
+	Score    int64  `json:"score"`  // 本局得分
+	Rating   int    `json:"rating"` // 本局结束后的等级分
+}
+
+// 对局事件类型
+const (
+	MatchEventDeal     = "deal"     // 发牌：Hands 各家手牌，Pokers 底牌，Removed 二人斗地主扣下的牌，Universals 癞子
+	MatchEventRob      = "rob"      // 抢地主：Player 是否抢 Rob
+	MatchEventBid      = "bid"      // 叫分：Player 叫的分数 Base，0 为不叫
+	MatchEventLandlord = "landlord" // 确定地主：Player 地主，Pokers 底牌
+	MatchEventDouble   = "double"   // 加倍：Player 加倍的玩家，Multiple 倍数，Open 是否明牌
+	MatchEventSkill    = "skill"    // 发动技能：Player 发动者，Skill 技能名，Hands 发动后的各家手牌
+	MatchEventPlay     = "play"     // 出牌：Player 出牌人，Pokers 出的牌，Faces 牌型
+	MatchEventPass     = "pass"     // 不出：Player
+	MatchEventSettle   = "settle"   // 结算：Base 底分，Multiple 倍数，Scores 各家得分
+)
+
+// MatchEvent is a step of a hand, a hand is replayed by applying its events in order.
+type MatchEvent struct {
+	Type       string                 `json:"type"`
+	Time       time.Time              `json:"time"`
+	Player     int64                  `json:"player,omitempty"`
+	Rob        bool                   `json:"rob,omitempty"`
+	Open       bool                   `json:"open,omitempty"`
+	Skill      string                 `json:"skill,omitempty"`
+	Pokers     model.Pokers           `json:"pokers,omitempty"`
+	Removed    model.Pokers           `json:"removed,omitempty"`
+	Faces      *model.Faces           `json:"faces,omitempty"`
+	Hands      map[int64]model.Pokers `json:"hands,omitempty"`
+	Universals []int                  `json:"universals,omitempty"`
+	Base       int                    `json:"base,omitempty"`
+	Multiple   int                    `json:"multiple,omitempty"`
+	Scores     map[int64]int64        `json:"scores,omitempty"`
+}
+
+// HasPlayer reports whether the player took part in the match.
+func (m Match) HasPlayer(playerId int64) bool {
+	for _, p := range m.Players {
+		if p.ID == playerId {
+			return true
+		}
+	}
+	return false
+}
+
+// ExportMatch writes the match of the id as indented JSON.
+func ExportMatch(id int64, w io.Writer) error {
+	match, err := store.GetMatch(id)
+	if err != nil {
+		return err
+	}
+	if match == nil {
+		return consts.ErrorsMatchNotExists
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(match)
+}
+
+var store Store = NewMemoryStore()
+
+// OpenStore switches to the file backed store at path, an empty path keeps everything in memory.
+func OpenStore(path string) error {
+	if path == "" {
+		SetStore(NewMemoryStore())
+		return nil
+	}
+	s, err := NewBoltStore(path)
+	if err != nil {
+		return err
+	}
+	SetStore(s)
+	return nil
+}
+
+func SetStore(s Store) {
+	store = s
+	resetIndex()
+}
+
+func GetStore() Store {
+	return store
+}
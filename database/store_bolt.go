@@ -0,0 +1,195 @@
+package database
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	bolt "go.etcd.io/bbolt"
+	"time"
+)
+
+var (
+	bucketPlayers       = []byte("players")
+	bucketMatches       = []byte("matches")
+	bucketPlayerMatches = []byte("player_matches")
+	bucketAccounts      = []byte("accounts")
+)
+
+// BoltStore keeps everything in a single BoltDB file, values are stored as json.
+type BoltStore struct {
+	db *bolt.DB
+}
+
+func NewBoltStore(path string) (*BoltStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 3 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketPlayers, bucketMatches, bucketPlayerMatches, bucketAccounts} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db}, nil
+}
+
+func (s *BoltStore) GetPlayer(id int64) (*PlayerRecord, error) {
+	var record *PlayerRecord
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucketPlayers).Get(itob(id))
+		if v == nil {
+			return nil
+		}
+		record = &PlayerRecord{}
+		return json.Unmarshal(v, record)
+	})
+	return record, err
+}
+
+func (s *BoltStore) SavePlayer(record *PlayerRecord) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		v, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket(bucketPlayers).Put(itob(record.ID), v)
+	})
+}
+
+func (s *BoltStore) GetPlayers() ([]*PlayerRecord, error) {
+	records := make([]*PlayerRecord, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketPlayers).ForEach(func(k, v []byte) error {
+			record := &PlayerRecord{}
+			if err := json.Unmarshal(v, record); err != nil {
+				return err
+			}
+			records = append(records, record)
+			return nil
+		})
+	})
+	return records, err
+}
+
+func (s *BoltStore) SaveMatch(match *Match) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		matches := tx.Bucket(bucketMatches)
+		if match.ID == 0 {
+			id, err := matches.NextSequence()
+			if err != nil {
+				return err
+			}
+			match.ID = int64(id)
+		}
+		v, err := json.Marshal(match)
+		if err != nil {
+			return err
+		}
+		if err = matches.Put(itob(match.ID), v); err != nil {
+			return err
+		}
+		// 玩家id + 对局id 作为索引，方便按玩家倒序查询，机器人不记录
+		index := tx.Bucket(bucketPlayerMatches)
+		for _, p := range match.Players {
+			if p.ID <= 0 {
+				continue
+			}
+			if err = index.Put(append(itob(p.ID), itob(match.ID)...), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *BoltStore) GetMatch(id int64) (*Match, error) {
+	var match *Match
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucketMatches).Get(itob(id))
+		if v == nil {
+			return nil
+		}
+		match = &Match{}
+		return json.Unmarshal(v, match)
+	})
+	return match, err
+}
+
+func (s *BoltStore) GetMatches(playerId int64, limit int) ([]*Match, error) {
+	list := make([]*Match, 0)
+	err := s.db.View(func(tx *bolt.Tx) error {
+		matches := tx.Bucket(bucketMatches)
+		prefix := itob(playerId)
+		c := tx.Bucket(bucketPlayerMatches).Cursor()
+		k, _ := c.Seek(itob(playerId + 1))
+		if k == nil {
+			k, _ = c.Last()
+		} else {
+			k, _ = c.Prev()
+		}
+		for ; k != nil && string(k[:8]) == string(prefix); k, _ = c.Prev() {
+			if limit > 0 && len(list) >= limit {
+				break
+			}
+			v := matches.Get(k[8:])
+			if v == nil {
+				continue
+			}
+			match := &Match{}
+			if err := json.Unmarshal(v, match); err != nil {
+				return err
+			}
+			list = append(list, match)
+		}
+		return nil
+	})
+	return list, err
+}
+
+func (s *BoltStore) GetAccount(name string) (*Account, error) {
+	var account *Account
+	err := s.db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(bucketAccounts).Get([]byte(name))
+		if v == nil {
+			return nil
+		}
+		account = &Account{}
+		return json.Unmarshal(v, account)
+	})
+	return account, err
+}
+
+func (s *BoltStore) SaveAccount(account *Account) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		accounts := tx.Bucket(bucketAccounts)
+		if account.ID == 0 {
+			id, err := accounts.NextSequence()
+			if err != nil {
+				return err
+			}
+			account.ID = int64(id)
+		}
+		v, err := json.Marshal(account)
+		if err != nil {
+			return err
+		}
+		return accounts.Put([]byte(account.Name), v)
+	})
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// itob encodes the id big endian so that keys are sorted by id.
+func itob(id int64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, uint64(id))
+	return b
+}
@@ -0,0 +1,115 @@
+package database
+
+import (
+	"sort"
+	"sync"
+)
+
+// MemoryStore keeps everything in memory, it is lost on restart and meant for tests.
+type MemoryStore struct {
+	sync.RWMutex
+
+	players   map[int64]PlayerRecord
+	matches   map[int64]Match
+	accounts  map[string]Account
+	matchId   int64
+	accountId int64
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		players:  map[int64]PlayerRecord{},
+		matches:  map[int64]Match{},
+		accounts: map[string]Account{},
+	}
+}
+
+func (s *MemoryStore) GetPlayer(id int64) (*PlayerRecord, error) {
+	s.RLock()
+	defer s.RUnlock()
+	if record, ok := s.players[id]; ok {
+		return &record, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) SavePlayer(record *PlayerRecord) error {
+	s.Lock()
+	defer s.Unlock()
+	s.players[record.ID] = *record
+	return nil
+}
+
+func (s *MemoryStore) GetPlayers() ([]*PlayerRecord, error) {
+	s.RLock()
+	defer s.RUnlock()
+	records := make([]*PlayerRecord, 0, len(s.players))
+	for _, record := range s.players {
+		record := record
+		records = append(records, &record)
+	}
+	return records, nil
+}
+
+func (s *MemoryStore) SaveMatch(match *Match) error {
+	s.Lock()
+	defer s.Unlock()
+	if match.ID == 0 {
+		s.matchId++
+		match.ID = s.matchId
+	}
+	s.matches[match.ID] = *match
+	return nil
+}
+
+func (s *MemoryStore) GetMatch(id int64) (*Match, error) {
+	s.RLock()
+	defer s.RUnlock()
+	if match, ok := s.matches[id]; ok {
+		return &match, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) GetMatches(playerId int64, limit int) ([]*Match, error) {
+	s.RLock()
+	defer s.RUnlock()
+	list := make([]*Match, 0)
+	for _, match := range s.matches {
+		if match.HasPlayer(playerId) {
+			match := match
+			list = append(list, &match)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID > list[j].ID
+	})
+	if limit > 0 && len(list) > limit {
+		list = list[:limit]
+	}
+	return list, nil
+}
+
+func (s *MemoryStore) GetAccount(name string) (*Account, error) {
+	s.RLock()
+	defer s.RUnlock()
+	if account, ok := s.accounts[name]; ok {
+		return &account, nil
+	}
+	return nil, nil
+}
+
+func (s *MemoryStore) SaveAccount(account *Account) error {
+	s.Lock()
+	defer s.Unlock()
+	if account.ID == 0 {
+		s.accountId++
+		account.ID = s.accountId
+	}
+	s.accounts[account.Name] = *account
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
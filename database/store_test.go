@@ -0,0 +1,121 @@
+package database
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/ratel-online/core/model"
+	"github.com/ratel-online/server/consts"
+	"path/filepath"
+	"testing"
+)
+
+func testStore(t *testing.T, s Store) {
+	record, err := s.GetPlayer(1)
+	if err != nil || record != nil {
+		t.Fatalf("expected no player, got %v %v", record, err)
+	}
+	if err = s.SavePlayer(&PlayerRecord{ID: 1, Name: "nico", Score: 10}); err != nil {
+		t.Fatal(err)
+	}
+	record, err = s.GetPlayer(1)
+	if err != nil || record == nil || record.Score != 10 || record.Name != "nico" {
+		t.Fatalf("unexpected player %v %v", record, err)
+	}
+	if err = s.SavePlayer(&PlayerRecord{ID: 2, Name: "ratel", Ratings: map[int]int{1: 1600}}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := s.GetPlayers()
+	if err != nil || len(records) != 2 {
+		t.Fatalf("unexpected players %v %v", records, err)
+	}
+	for _, record := range records {
+		if record.ID == 2 && record.Ratings[1] != 1600 {
+			t.Fatalf("unexpected ratings %v", record.Ratings)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		match := &Match{Players: []MatchPlayer{{ID: 1}, {ID: int64(2 + i%2)}, {ID: -1}}}
+		if err = s.SaveMatch(match); err != nil {
+			t.Fatal(err)
+		}
+		if match.ID != int64(i+1) {
+			t.Fatalf("expected match id %d, got %d", i+1, match.ID)
+		}
+	}
+	matches, err := s.GetMatches(1, 2)
+	if err != nil || len(matches) != 2 || matches[0].ID != 3 || matches[1].ID != 2 {
+		t.Fatalf("unexpected matches of player 1 %v %v", matches, err)
+	}
+	matches, err = s.GetMatches(3, 0)
+	if err != nil || len(matches) != 1 || matches[0].ID != 2 {
+		t.Fatalf("unexpected matches of player 3 %v %v", matches, err)
+	}
+	match, err := s.GetMatch(3)
+	if err != nil || match == nil || len(match.Players) != 3 {
+		t.Fatalf("unexpected match %v %v", match, err)
+	}
+	match = &Match{Version: MatchVersion, Events: []MatchEvent{
+		{Type: MatchEventDeal, Hands: map[int64]model.Pokers{1: {{Key: 3, Val: 1, Desc: "3"}}}},
+		{Type: MatchEventSettle, Base: 1, Multiple: 2, Scores: map[int64]int64{1: 2, 2: -2}},
+	}}
+	if err = s.SaveMatch(match); err != nil {
+		t.Fatal(err)
+	}
+	match, err = s.GetMatch(match.ID)
+	if err != nil || match == nil || len(match.Events) != 2 {
+		t.Fatalf("unexpected match %v %v", match, err)
+	}
+	if match.Events[0].Hands[1][0].Key != 3 || match.Events[1].Scores[2] != -2 {
+		t.Fatalf("unexpected events %+v", match.Events)
+	}
+	account := &Account{Name: "nico", Password: "hash"}
+	if err = s.SaveAccount(account); err != nil || account.ID != 1 {
+		t.Fatalf("unexpected account id %d %v", account.ID, err)
+	}
+	account, err = s.GetAccount("nico")
+	if err != nil || account == nil || account.ID != 1 || account.Password != "hash" {
+		t.Fatalf("unexpected account %v %v", account, err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestBoltStore(t *testing.T) {
+	s, err := NewBoltStore(filepath.Join(t.TempDir(), "ratel.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	testStore(t, s)
+}
+
+func TestExportMatch(t *testing.T) {
+	defer SetStore(GetStore())
+	SetStore(NewMemoryStore())
+	if err := ExportMatch(1, &bytes.Buffer{}); err != consts.ErrorsMatchNotExists {
+		t.Fatalf("expected match not exists, got %v", err)
+	}
+	match := &Match{Version: MatchVersion, Events: []MatchEvent{{Type: MatchEventPass, Player: 1}}}
+	_ = store.SaveMatch(match)
+	buf := &bytes.Buffer{}
+	if err := ExportMatch(match.ID, buf); err != nil {
+		t.Fatal(err)
+	}
+	exported := &Match{}
+	if err := json.Unmarshal(buf.Bytes(), exported); err != nil || exported.Events[0].Type != MatchEventPass {
+		t.Fatalf("unexpected export %s %v", buf.String(), err)
+	}
+}
+
+func TestClearEvents(t *testing.T) {
+	game := &Game{}
+	game.Record(MatchEvent{Type: MatchEventDeal})
+	game.Record(MatchEvent{Type: MatchEventRob, Player: 1})
+	game.ClearEvents()
+	game.Record(MatchEvent{Type: MatchEventDeal})
+	if len(game.Events) != 1 || game.Events[0].Type != MatchEventDeal {
+		t.Fatalf("expected only the redeal to be logged, got %v", game.Events)
+	}
+}
@@ -6,4 +6,15 @@ require (
 	github.com/awesome-cap/hashmap v0.0.0-20211211100532-e3300ac4ae14
 	github.com/gorilla/websocket v1.4.2
 	github.com/ratel-online/core v0.0.0-20220126124756-4f993c93705e
+	go.etcd.io/bbolt v1.3.6
+	golang.org/x/crypto v0.0.0-20220112180741-5e0467b6c7ce
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/awesome-cap/im v0.0.0-20210720090440-7556eb92965d // indirect
+	github.com/json-iterator/go v1.1.12 // indirect
+	github.com/modern-go/concurrent v0.0.0-20180228061459-e0a39a4cb421 // indirect
+	github.com/modern-go/reflect2 v1.0.2 // indirect
+	golang.org/x/sys v0.0.0-20210615035016-665e8c7367d1 // indirect
 )
@@ -4,25 +4,129 @@ import (
 	"flag"
 	"github.com/ratel-online/core/log"
 	"github.com/ratel-online/core/util/async"
+	"github.com/ratel-online/server/auth"
+	"github.com/ratel-online/server/config"
+	"github.com/ratel-online/server/database"
 	"github.com/ratel-online/server/network"
+	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
+	"time"
 )
 
+// 管理接口的令牌从环境变量读取，不放在命令行上，以免被 ps 看到
+const adminTokenEnv = "RATEL_ADMIN_TOKEN"
+
 var (
-	Wsport  int
-	Tcpport int
+	Wsport     int
+	Tcpport    int
+	DataFile   string
+	AuthMode   string
+	AuthSecret string
+	AuthGuest  bool
+	Export     int64
+	AdminPort  int
+	Drain      int
+	ConfigFile string
 )
 
 func main() {
 	flag.IntVar(&Wsport, "w", 9998, "WebsocketServer Port")
 	flag.IntVar(&Tcpport, "t", 9999, "TcpServer Port")
+	flag.StringVar(&DataFile, "d", "", "Data file of the persistent storage, keep data in memory if empty")
+	flag.StringVar(&AuthMode, "a", auth.ModeAccount, "Auth mode, token, account or guest, trust is only meant for development")
+	flag.StringVar(&AuthSecret, "s", "", "Secret to verify tokens of the token auth mode")
+	flag.BoolVar(&AuthGuest, "g", false, "Allow guests to login along with the auth mode")
+	flag.Int64Var(&Export, "x", 0, "Export the match of the id from the data file as JSON and exit")
+	flag.IntVar(&AdminPort, "m", 0, "Admin HTTP API Port, disabled if 0, the bearer token is read from "+adminTokenEnv)
+	flag.IntVar(&Drain, "q", 300, "Seconds running games may take to finish on shutdown, they are aborted as void afterwards")
+	flag.StringVar(&ConfigFile, "c", "", "YAML config file, reloaded on SIGHUP, defaults are used if empty")
 	flag.Parse()
 
-	async.Async(func() {
-		wsServer := network.NewWebsocketServer(":" + strconv.Itoa(Wsport))
-		log.Panic(wsServer.Serve())
-	})
+	if ConfigFile != "" {
+		if err := config.Load(ConfigFile); err != nil {
+			log.Panic(err)
+			return
+		}
+		reload := make(chan os.Signal, 1)
+		signal.Notify(reload, syscall.SIGHUP)
+		async.Async(func() {
+			for range reload {
+				if err := config.Load(ConfigFile); err != nil {
+					log.Errorf("config reload failed, keep the current one: %v\n", err)
+					continue
+				}
+				log.Infof("config reloaded from %s\n", ConfigFile)
+			}
+		})
+	}
+
+	err := database.OpenStore(DataFile)
+	if err != nil {
+		log.Panic(err)
+		return
+	}
+	defer database.GetStore().Close()
+
+	if Export > 0 {
+		if err = database.ExportMatch(Export, os.Stdout); err != nil {
+			log.Error(err)
+		}
+		return
+	}
+
+	authenticator, err := auth.New(AuthMode, AuthSecret)
+	if err != nil {
+		log.Panic(err)
+		return
+	}
+	if AuthGuest && AuthMode != auth.ModeGuest {
+		authenticator = auth.NewGuest(authenticator)
+	}
+	network.SetAuthenticator(authenticator)
+
+	servers := []network.Network{
+		network.NewWebsocketServer(":" + strconv.Itoa(Wsport)),
+		network.NewTcpServer(":" + strconv.Itoa(Tcpport)),
+	}
+	var admin network.Network
+	if AdminPort > 0 {
+		admin = network.NewAdminServer(":"+strconv.Itoa(AdminPort), os.Getenv(adminTokenEnv))
+		servers = append(servers, admin)
+	}
+	errs := make(chan error, len(servers))
+	for _, server := range servers {
+		server := server
+		async.Async(func() {
+			if err := server.Serve(); err != nil {
+				errs <- err
+			}
+		})
+	}
 
-	server := network.NewTcpServer(":" + strconv.Itoa(Tcpport))
-	log.Panic(server.Serve())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT)
+	select {
+	case sig := <-signals:
+		log.Infof("received %v, shutting down\n", sig)
+	case err = <-errs:
+		log.Panic(err)
+	}
+	for _, server := range servers {
+		// 管理接口在对局结束前仍然可用
+		if server == admin {
+			continue
+		}
+		if err := server.Close(); err != nil {
+			log.Error(err)
+		}
+	}
+	database.Shutdown(time.Duration(Drain) * time.Second)
+	if admin != nil {
+		if err := admin.Close(); err != nil {
+			log.Error(err)
+		}
+	}
+	log.Info("server stopped")
 }
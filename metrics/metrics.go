@@ -0,0 +1,246 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// collector is a metric written in the Prometheus text exposition format.
+type collector interface {
+	write(buf *bytes.Buffer)
+}
+
+var (
+	registryLock sync.Mutex
+	registry     []collector
+)
+
+func register(c collector) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry = append(registry, c)
+}
+
+// Write writes every registered metric in the Prometheus text format.
+func Write(w io.Writer) error {
+	registryLock.Lock()
+	list := append([]collector{}, registry...)
+	registryLock.Unlock()
+	buf := bytes.Buffer{}
+	for _, c := range list {
+		c.write(&buf)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Handler serves the metrics to the Prometheus scraper.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		_ = Write(w)
+	})
+}
+
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (d desc) header(buf *bytes.Buffer, typ string) {
+	buf.WriteString(fmt.Sprintf("# HELP %s %s\n# TYPE %s %s\n", d.name, d.help, d.name, typ))
+}
+
+// series formats the name and the labels of a sample, extra is appended as is, e.g. the le of buckets.
+func (d desc) series(name string, values []string, extra string) string {
+	pairs := make([]string, 0, len(d.labels)+1)
+	for i, label := range d.labels {
+		value := ""
+		if i < len(values) {
+			value = values[i]
+		}
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", label, escape(value)))
+	}
+	if extra != "" {
+		pairs = append(pairs, extra)
+	}
+	if len(pairs) == 0 {
+		return name
+	}
+	return name + "{" + strings.Join(pairs, ",") + "}"
+}
+
+// Counter counts events by its labels.
+type Counter struct {
+	desc
+	lock   sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter registers a counter, the label values are given in the order of the label names.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{desc: desc{name: name, help: help, labels: labels}, values: map[string]float64{}}
+	register(c)
+	return c
+}
+
+func (c *Counter) Inc(values ...string) {
+	c.Add(1, values...)
+}
+
+func (c *Counter) Add(delta float64, values ...string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.values[key(values)] += delta
+}
+
+// Value returns the count of the label values.
+func (c *Counter) Value(values ...string) float64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.values[key(values)]
+}
+
+func (c *Counter) write(buf *bytes.Buffer) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.header(buf, "counter")
+	for _, k := range sortedKeys(c.values) {
+		buf.WriteString(fmt.Sprintf("%s %s\n", c.series(c.name, split(k), ""), format(c.values[k])))
+	}
+}
+
+// Histogram samples observations into buckets, the sum and the count give the average.
+type Histogram struct {
+	desc
+	buckets []float64
+	lock    sync.Mutex
+	values  map[string]*histogramValue
+}
+
+type histogramValue struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram registers a histogram with the upper bounds of its buckets in ascending order.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{desc: desc{name: name, help: help, labels: labels}, buckets: buckets, values: map[string]*histogramValue{}}
+	register(h)
+	return h
+}
+
+func (h *Histogram) Observe(v float64, values ...string) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	k := key(values)
+	hv := h.values[k]
+	if hv == nil {
+		hv = &histogramValue{counts: make([]uint64, len(h.buckets))}
+		h.values[k] = hv
+	}
+	for i, bound := range h.buckets {
+		if v <= bound {
+			hv.counts[i]++
+		}
+	}
+	hv.sum += v
+	hv.count++
+}
+
+// Since observes the seconds elapsed since start, it is meant to be deferred.
+func (h *Histogram) Since(start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
+
+// Count returns the number of observations of the label values.
+func (h *Histogram) Count(values ...string) uint64 {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if hv := h.values[key(values)]; hv != nil {
+		return hv.count
+	}
+	return 0
+}
+
+func (h *Histogram) write(buf *bytes.Buffer) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.header(buf, "histogram")
+	keys := make([]string, 0, len(h.values))
+	for k := range h.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		hv, values := h.values[k], split(k)
+		for i, bound := range h.buckets {
+			buf.WriteString(fmt.Sprintf("%s %d\n", h.series(h.name+"_bucket", values, fmt.Sprintf("le=\"%s\"", format(bound))), hv.counts[i]))
+		}
+		buf.WriteString(fmt.Sprintf("%s %d\n", h.series(h.name+"_bucket", values, "le=\"+Inf\""), hv.count))
+		buf.WriteString(fmt.Sprintf("%s %s\n", h.series(h.name+"_sum", values, ""), format(hv.sum)))
+		buf.WriteString(fmt.Sprintf("%s %d\n", h.series(h.name+"_count", values, ""), hv.count))
+	}
+}
+
+// Sample is a value of a gauge, the label values are in the order of the label names.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// GaugeFunc reads its samples from the state of the server on every scrape.
+type GaugeFunc struct {
+	desc
+	collect func() []Sample
+}
+
+func NewGaugeFunc(name, help string, collect func() []Sample, labels ...string) *GaugeFunc {
+	g := &GaugeFunc{desc: desc{name: name, help: help, labels: labels}, collect: collect}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(buf *bytes.Buffer) {
+	g.header(buf, "gauge")
+	for _, sample := range g.collect() {
+		buf.WriteString(fmt.Sprintf("%s %s\n", g.series(g.name, sample.Labels, ""), format(sample.Value)))
+	}
+}
+
+// 标签值用不可见字符连接作为map的key
+const separator = "\xff"
+
+func key(values []string) string {
+	return strings.Join(values, separator)
+}
+
+func split(k string) []string {
+	return strings.Split(k, separator)
+}
+
+func sortedKeys(values map[string]float64) []string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func format(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
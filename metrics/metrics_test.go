@@ -0,0 +1,50 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	counter := NewCounter("test_events_total", "Events.", "type")
+	counter.Inc("a")
+	counter.Add(2, `b"c`)
+	histogram := NewHistogram("test_duration_seconds", "Durations.", []float64{1, 5})
+	histogram.Observe(0.5)
+	histogram.Observe(3)
+	NewGaugeFunc("test_gauge", "Gauge.", func() []Sample {
+		return []Sample{{Labels: []string{"x", "y"}, Value: 7}}
+	}, "k1", "k2")
+
+	buf := bytes.Buffer{}
+	if err := Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"# TYPE test_events_total counter",
+		`test_events_total{type="a"} 1`,
+		`test_events_total{type="b\"c"} 2`,
+		"# TYPE test_duration_seconds histogram",
+		`test_duration_seconds_bucket{le="1"} 1`,
+		`test_duration_seconds_bucket{le="5"} 2`,
+		`test_duration_seconds_bucket{le="+Inf"} 2`,
+		"test_duration_seconds_sum 3.5",
+		"test_duration_seconds_count 2",
+		`test_gauge{k1="x",k2="y"} 7`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Fatalf("missing %q in\n%s", line, buf.String())
+		}
+	}
+	if counter.Value("a") != 1 || histogram.Count() != 2 {
+		t.Fatalf("unexpected values %v %d", counter.Value("a"), histogram.Count())
+	}
+
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(w.Body.String(), "ratel_games_started_total") {
+		t.Fatalf("server metrics not registered:\n%s", w.Body.String())
+	}
+}
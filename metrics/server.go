@@ -0,0 +1,13 @@
+package metrics
+
+// 服务端的指标，房间和在线人数等状态类指标由 database 包在抓取时统计
+var (
+	GamesStarted  = NewCounter("ratel_games_started_total", "Games started by game type.", "type")
+	GamesFinished = NewCounter("ratel_games_finished_total", "Games settled by game type.", "type")
+	HandDuration  = NewHistogram("ratel_hand_duration_seconds", "Duration of settled hands by game type.",
+		[]float64{30, 60, 120, 180, 300, 600, 900}, "type")
+	Timeouts     = NewCounter("ratel_turn_timeouts_total", "Turns players let time out, by turn, rob or play.", "turn")
+	AuthFailures = NewCounter("ratel_auth_failures_total", "Failed logins of new connections.")
+	WriteLatency = NewHistogram("ratel_write_latency_seconds", "Latency of writing a message to a player, throttling included.",
+		[]float64{0.001, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1})
+)
@@ -0,0 +1,271 @@
+package network
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"github.com/ratel-online/core/log"
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/metrics"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Admin serves the HTTP API of the operators, every request has to carry the admin token
+// as "Authorization: Bearer <token>".
+type Admin struct {
+	addr   string
+	token  string
+	mux    *http.ServeMux
+	server *http.Server
+}
+
+func NewAdminServer(addr, token string) Admin {
+	a := Admin{addr: addr, token: token, mux: http.NewServeMux()}
+	a.server = &http.Server{Addr: addr, Handler: a}
+	a.mux.HandleFunc("/admin/rooms", a.rooms)
+	a.mux.HandleFunc("/admin/rooms/", a.room)
+	a.mux.HandleFunc("/admin/players", a.players)
+	a.mux.HandleFunc("/admin/players/", a.player)
+	a.mux.HandleFunc("/admin/announcement", a.announcement)
+	a.mux.HandleFunc("/admin/game-types", a.gameTypes)
+	a.mux.Handle("/metrics", metrics.Handler())
+	return a
+}
+
+func (a Admin) Serve() error {
+	if a.token == "" {
+		return consts.ErrorsAdminTokenMissing
+	}
+	log.Infof("Admin server listening on %s\n", a.addr)
+	err := a.server.ListenAndServe()
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+func (a Admin) Close() error {
+	return a.server.Close()
+}
+
+func (a Admin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if a.token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
+		log.Infof("admin auth failed, ip %s\n", r.RemoteAddr)
+		writeError(w, http.StatusUnauthorized, consts.ErrorsAuthFail)
+		return
+	}
+	a.mux.ServeHTTP(w, r)
+}
+
+type adminRoom struct {
+	modelx.Room
+	Robots     int                `json:"robots"`
+	Spectators int                `json:"spectators"`
+	MaxPlayer  int                `json:"maxPlayer"`
+	Locked     bool               `json:"locked"` // 是否设置了密码
+	Properties map[string]bool    `json:"properties"`
+	ActiveTime time.Time          `json:"activeTime"`
+	Members    []adminPlayer      `json:"members"`
+	Game       *database.Snapshot `json:"game,omitempty"`
+}
+
+type adminPlayer struct {
+	ID      int64  `json:"id"`
+	Name    string `json:"name"`
+	IP      string `json:"ip"`
+	Score   int64  `json:"score"`
+	RoomID  int64  `json:"roomId"`
+	WatchID int64  `json:"watchId"`
+	State   int    `json:"state"`
+	Online  bool   `json:"online"`
+	Robot   bool   `json:"robot"`
+	Auto    bool   `json:"auto"`
+}
+
+// GET /admin/rooms
+func (a Admin) rooms(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, consts.ErrorsInputInvalid)
+		return
+	}
+	list := make([]adminRoom, 0)
+	for _, room := range database.GetRooms() {
+		list = append(list, newAdminRoom(room, false))
+	}
+	writeJSON(w, list)
+}
+
+// GET /admin/rooms/{id} with the game state, POST /admin/rooms/{id}/dissolve
+func (a Admin) room(w http.ResponseWriter, r *http.Request) {
+	id, action, err := parsePath(r.URL.Path, "/admin/rooms/")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	switch {
+	case r.Method == http.MethodGet && action == "":
+		room := database.GetRoom(id)
+		if room == nil {
+			writeError(w, http.StatusNotFound, consts.ErrorsRoomInvalid)
+			return
+		}
+		writeJSON(w, newAdminRoom(room, true))
+	case r.Method == http.MethodPost && action == "dissolve":
+		if err = database.DissolveRoom(id); err != nil {
+			writeError(w, http.StatusNotFound, err)
+			return
+		}
+		log.Infof("admin dissolved room %d\n", id)
+		writeJSON(w, map[string]int64{"id": id})
+	default:
+		writeError(w, http.StatusNotFound, consts.ErrorsInputInvalid)
+	}
+}
+
+// GET /admin/players
+func (a Admin) players(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeError(w, http.StatusMethodNotAllowed, consts.ErrorsInputInvalid)
+		return
+	}
+	list := make([]adminPlayer, 0)
+	for _, player := range database.OnlinePlayers() {
+		list = append(list, newAdminPlayer(player))
+	}
+	writeJSON(w, list)
+}
+
+// POST /admin/players/{id}/kick
+func (a Admin) player(w http.ResponseWriter, r *http.Request) {
+	id, action, err := parsePath(r.URL.Path, "/admin/players/")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if r.Method != http.MethodPost || action != "kick" {
+		writeError(w, http.StatusNotFound, consts.ErrorsInputInvalid)
+		return
+	}
+	if err = database.Kick(id); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	log.Infof("admin kicked player %d\n", id)
+	writeJSON(w, map[string]int64{"id": id})
+}
+
+// POST /admin/announcement {"msg": "..."}
+func (a Admin) announcement(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, consts.ErrorsInputInvalid)
+		return
+	}
+	body := struct {
+		Msg string `json:"msg"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || strings.TrimSpace(body.Msg) == "" {
+		writeError(w, http.StatusBadRequest, consts.ErrorsInputInvalid)
+		return
+	}
+	writeJSON(w, map[string]int{"players": database.Announce(strings.TrimSpace(body.Msg))})
+}
+
+// GET /admin/game-types, PUT /admin/game-types {"ids": [1, 2]}
+func (a Admin) gameTypes(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		body := struct {
+			IDs []int `json:"ids"`
+		}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			writeError(w, http.StatusBadRequest, consts.ErrorsInputInvalid)
+			return
+		}
+		if err := database.SetGameTypes(body.IDs); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		log.Infof("admin changed game types to %v\n", body.IDs)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, consts.ErrorsInputInvalid)
+		return
+	}
+	options := make([]modelx.Option, 0)
+	for _, id := range database.GameTypes() {
+		options = append(options, modelx.Option{ID: id, Name: consts.GameTypes[id]})
+	}
+	writeJSON(w, options)
+}
+
+// newAdminRoom describes the room under its lock, the game is taken from its last snapshot
+// since the game goroutines change it without the lock.
+func newAdminRoom(room *database.Room, withGame bool) adminRoom {
+	room.Lock()
+	defer room.Unlock()
+	info := adminRoom{
+		Room:       room.Model(),
+		Robots:     room.Robots,
+		Spectators: room.Spectators,
+		MaxPlayer:  room.MaxPlayer,
+		Locked:     room.Password != "",
+		Properties: room.GetProperties(),
+		ActiveTime: room.ActiveTime,
+		Members:    make([]adminPlayer, 0),
+	}
+	for id := range database.RoomPlayers(room.ID) {
+		if player := database.GetPlayer(id); player != nil {
+			info.Members = append(info.Members, newAdminPlayer(player))
+		}
+	}
+	if game := room.Game; withGame && game != nil {
+		info.Game = game.Snapshot()
+	}
+	return info
+}
+
+func newAdminPlayer(player *database.Player) adminPlayer {
+	return adminPlayer{
+		ID:      player.ID,
+		Name:    player.Name,
+		IP:      player.IP,
+		Score:   player.Score,
+		RoomID:  player.RoomID,
+		WatchID: player.WatchID,
+		State:   int(player.GetState()),
+		Online:  database.IsOnline(player.ID),
+		Robot:   player.IsRobot(),
+		Auto:    player.Auto(),
+	}
+}
+
+// parsePath splits "{prefix}{id}/{action}", the action is optional.
+func parsePath(path, prefix string) (int64, string, error) {
+	parts := strings.SplitN(strings.TrimPrefix(path, prefix), "/", 2)
+	id, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", consts.ErrorsInputInvalid
+	}
+	if len(parts) == 2 {
+		return id, parts[1], nil
+	}
+	return id, "", nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error(err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": strings.TrimSpace(err.Error())})
+}
@@ -0,0 +1,72 @@
+package network
+
+import (
+	"encoding/json"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func request(admin Admin, method, path, token, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	admin.ServeHTTP(w, r)
+	return w
+}
+
+func TestAdmin(t *testing.T) {
+	admin := NewAdminServer(":0", "secret")
+	if w := request(admin, http.MethodGet, "/admin/rooms", "", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status %d without token", w.Code)
+	}
+	if w := request(admin, http.MethodGet, "/admin/rooms", "wrong", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("unexpected status %d with wrong token", w.Code)
+	}
+
+	room := database.CreateRoom(1, "", 3)
+	if _, err := database.AddRobot(room.ID, consts.RobotLevelEasy); err != nil {
+		t.Fatal(err)
+	}
+	w := request(admin, http.MethodGet, "/admin/rooms", "secret", "")
+	rooms := make([]adminRoom, 0)
+	if err := json.Unmarshal(w.Body.Bytes(), &rooms); err != nil || len(rooms) != 1 || rooms[0].ID != room.ID || len(rooms[0].Members) != 1 {
+		t.Fatalf("unexpected rooms %s %v", w.Body, err)
+	}
+	if w = request(admin, http.MethodGet, "/admin/rooms/x", "secret", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status %d of invalid id", w.Code)
+	}
+	if w = request(admin, http.MethodPost, "/admin/rooms/"+strconv.FormatInt(room.ID, 10)+"/dissolve", "secret", ""); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d of dissolve: %s", w.Code, w.Body)
+	}
+	if database.GetRoom(room.ID) != nil {
+		t.Fatal("room not dissolved")
+	}
+	if w = request(admin, http.MethodGet, "/admin/rooms/"+strconv.FormatInt(room.ID, 10), "secret", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status %d of dissolved room", w.Code)
+	}
+	if w = request(admin, http.MethodPost, "/admin/players/42/kick", "secret", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("unexpected status %d of kicking nobody", w.Code)
+	}
+
+	if w = request(admin, http.MethodGet, "/metrics", "secret", ""); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "ratel_rooms{") {
+		t.Fatalf("unexpected metrics %d: %s", w.Code, w.Body)
+	}
+
+	if w = request(admin, http.MethodPut, "/admin/game-types", "secret", `{"ids": [99]}`); w.Code != http.StatusBadRequest {
+		t.Fatalf("unexpected status %d of invalid game type", w.Code)
+	}
+	if w = request(admin, http.MethodPut, "/admin/game-types", "secret", `{"ids": [2, 1]}`); w.Code != http.StatusOK {
+		t.Fatalf("unexpected status %d of game types: %s", w.Code, w.Body)
+	}
+	defer database.SetGameTypes(consts.GameTypesIds)
+	if types := database.GameTypes(); len(types) != 2 || types[0] != consts.GameTypeLaiZi || database.IsGameTypeOffered(consts.GameTypeSkill) {
+		t.Fatalf("unexpected game types %v", types)
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"github.com/ratel-online/core/network"
 	"github.com/ratel-online/core/protocol"
 	"github.com/ratel-online/core/util/async"
+	"github.com/ratel-online/server/auth"
+	"github.com/ratel-online/server/config"
 	"github.com/ratel-online/server/consts"
 	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/metrics"
 	"github.com/ratel-online/server/state"
 	"time"
 )
@@ -15,6 +18,16 @@ import (
 // Network is interface of all kinds of network.
 type Network interface {
 	Serve() error
+	// Close stops listening, Serve returns nil afterwards.
+	Close() error
+}
+
+// 未配置时使用账号登录，从不默认信任客户端
+var authenticator auth.Authenticator = auth.Account{}
+
+// SetAuthenticator changes how new connections are authenticated.
+func SetAuthenticator(a auth.Authenticator) {
+	authenticator = a
 }
 
 func handle(rwc protocol.ReadWriteCloser) error {
@@ -27,40 +40,50 @@ func handle(rwc protocol.ReadWriteCloser) error {
 		}
 	}()
 	log.Info("new player connected! ")
-	authInfo, err := loginAuth(c)
-	if err != nil || authInfo.ID == 0 {
+	authInfo, mode, err := loginAuth(c)
+	var player *database.Player
+	reconnected := false
+	if err == nil {
+		player, reconnected, err = database.Login(c, authInfo, mode)
+	}
+	if err != nil {
+		metrics.AuthFailures.Inc()
+		log.Infof("player auth failed, ip %s, %v\n", c.IP(), err)
 		_ = c.Write(protocol.ErrorPacket(err))
 		return err
 	}
-	player := database.Connected(c, authInfo)
-	log.Infof("player auth accessed, ip %s, %d:%s\n", player.IP, authInfo.ID, authInfo.Name)
-	go state.Run(player)
+	if reconnected {
+		log.Infof("player reconnected, ip %s, %d:%s\n", player.IP, authInfo.ID, authInfo.Name)
+		go state.Resume(player)
+	} else {
+		log.Infof("player auth accessed, ip %s, %d:%s\n", player.IP, authInfo.ID, authInfo.Name)
+		go state.Run(player)
+	}
 	defer player.Offline()
 	return player.Listening()
 }
 
-// 登陆验签
-func loginAuth(c *network.Conn) (*model.AuthInfo, error) {
-	authChan := make(chan *model.AuthInfo)
-	defer close(authChan)
+// 登陆验签，同时协商连接使用的协议
+func loginAuth(c *network.Conn) (*model.AuthInfo, int, error) {
+	packetChan := make(chan *protocol.Packet)
+	defer close(packetChan)
 	async.Async(func() {
 		packet, err := c.Read()
 		if err != nil {
 			log.Error(err)
 			return
 		}
-		authInfo := &model.AuthInfo{}
-		err = packet.Unmarshal(authInfo)
-		if err != nil {
-			log.Error(err)
-			return
-		}
-		authChan <- authInfo
+		packetChan <- packet
 	})
 	select {
-	case authInfo := <-authChan:
-		return authInfo, nil
-	case <-time.After(3 * time.Second):
-		return nil, consts.ErrorsAuthFail
+	case packet := <-packetChan:
+		mode, err := auth.Protocol(packet)
+		if err != nil {
+			return nil, 0, err
+		}
+		info, err := authenticator.Auth(packet)
+		return info, mode, err
+	case <-time.After(config.Get().AuthTimeout):
+		return nil, 0, consts.ErrorsAuthFail
 	}
 }
@@ -1,29 +1,46 @@
 package network
 
 import (
+    "errors"
     "github.com/ratel-online/core/log"
     "github.com/ratel-online/core/protocol"
     "github.com/ratel-online/core/util/async"
     "net"
+    "sync"
 )
 
 type Tcp struct {
-    addr string
+    addr     string
+    lock     sync.Mutex // Serve 和 Close 在不同协程中调用
+    listener net.Listener
+    closed   bool
 }
 
-func NewTcpServer(addr string) Tcp {
-    return Tcp{addr: addr}
+func NewTcpServer(addr string) *Tcp {
+    return &Tcp{addr: addr}
 }
 
-func (t Tcp) Serve() error {
+func (t *Tcp) Serve() error {
+    t.lock.Lock()
+    if t.closed {
+        // 还没开始监听就收到了停服信号
+        t.lock.Unlock()
+        return nil
+    }
     listener, err := net.Listen("tcp", t.addr)
     if err != nil {
+        t.lock.Unlock()
         log.Error(err)
         return err
     }
+    t.listener = listener
+    t.lock.Unlock()
     log.Infof("Tcp server listening on %s\n", t.addr)
     for {
         conn, err := listener.Accept()
+        if errors.Is(err, net.ErrClosed) {
+            return nil
+        }
         if err != nil {
             log.Infof("listener.Accept err %v\n", err)
             continue
@@ -36,3 +53,15 @@ func (t Tcp) Serve() error {
         })
     }
 }
+
+// Close stops accepting new connections, the connected players are left alone.
+// Serve does not start listening any more once it is closed.
+func (t *Tcp) Close() error {
+    t.lock.Lock()
+    defer t.lock.Unlock()
+    t.closed = true
+    if t.listener == nil {
+        return nil
+    }
+    return t.listener.Close()
+}
@@ -8,7 +8,8 @@ import (
 )
 
 type Websocket struct {
-    addr string
+    addr   string
+    server *http.Server
 }
 
 var upgrader = websocket.Upgrader{
@@ -19,14 +20,24 @@ var upgrader = websocket.Upgrader{
     },
 }
 
-func NewWebsocketServer(addr string) Websocket {
-    return Websocket{addr: addr}
+func NewWebsocketServer(addr string) *Websocket {
+    mux := http.NewServeMux()
+    mux.HandleFunc("/ws", serveWs)
+    return &Websocket{addr: addr, server: &http.Server{Addr: addr, Handler: mux}}
 }
 
-func (w Websocket) Serve() error {
-    http.HandleFunc("/ws", serveWs)
+func (w *Websocket) Serve() error {
     log.Infof("Websocket server listener on %s\n", w.addr)
-    return http.ListenAndServe(w.addr, nil)
+    err := w.server.ListenAndServe()
+    if err == http.ErrServerClosed {
+        return nil
+    }
+    return err
+}
+
+// Close stops accepting new connections, upgraded connections are not touched.
+func (w *Websocket) Close() error {
+    return w.server.Close()
 }
 
 func serveWs(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,85 @@
+// Package rating implements the Elo skill rating of the players. It is kept per game type and
+// updated after every hand, independently of the settlement score, so that it reflects skill
+// rather than volume of play.
+package rating
+
+import "math"
+
+const (
+	// K is the largest change of a rating in a single hand.
+	K = 32
+	// LandlordHandicap is how much weaker the landlord is considered for every peasant beyond two,
+	// winning alone gets harder as the table grows.
+	LandlordHandicap = 50
+)
+
+// Seat is a player of a finished hand.
+type Seat struct {
+	ID       int64
+	Rating   int
+	Landlord bool
+	Winner   bool
+	Robot    bool
+}
+
+// Rated reports whether the hand changes ratings. Hands with robots are unrated, robots always
+// play at the default rating and would let anyone farm rating by beating them.
+func Rated(seats []Seat) bool {
+	for _, seat := range seats {
+		if seat.Robot {
+			return false
+		}
+	}
+	return true
+}
+
+// Expected returns the expected score of a player rated a against a player rated b.
+func Expected(a, b float64) float64 {
+	return 1 / (1 + math.Pow(10, (b-a)/400))
+}
+
+// Update returns the rating change of every seat of a hand.
+//
+// Every winner plays one Elo game against every loser. The stake of a game is K divided by the
+// size of the larger team, so nobody moves more than K in a hand: the lone landlord carries the
+// whole stake while the peasants share it, and in skill mode the winner takes a share from each
+// player. The changes sum to zero up to rounding, nobody moves in a hand which is not rated.
+func Update(seats []Seat) map[int64]int {
+	winners, losers := make([]Seat, 0), make([]Seat, 0)
+	peasants := 0
+	for _, seat := range seats {
+		if seat.Winner {
+			winners = append(winners, seat)
+		} else {
+			losers = append(losers, seat)
+		}
+		if !seat.Landlord {
+			peasants++
+		}
+	}
+	deltas := map[int64]int{}
+	if len(winners) == 0 || len(losers) == 0 || !Rated(seats) {
+		return deltas
+	}
+	stake := float64(K) / math.Max(float64(len(winners)), float64(len(losers)))
+	changes := map[int64]float64{}
+	for _, w := range winners {
+		for _, l := range losers {
+			change := stake * (1 - Expected(strength(w, peasants), strength(l, peasants)))
+			changes[w.ID] += change
+			changes[l.ID] -= change
+		}
+	}
+	for id, change := range changes {
+		deltas[id] = int(math.Round(change))
+	}
+	return deltas
+}
+
+// strength is the rating of the seat adjusted by the landlord handicap.
+func strength(seat Seat, peasants int) float64 {
+	if seat.Landlord && peasants > 2 {
+		return float64(seat.Rating - LandlordHandicap*(peasants-2))
+	}
+	return float64(seat.Rating)
+}
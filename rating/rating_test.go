@@ -0,0 +1,45 @@
+package rating
+
+import "testing"
+
+func TestUpdate(t *testing.T) {
+	deltas := Update([]Seat{
+		{ID: 1, Rating: 1500, Landlord: true, Winner: true},
+		{ID: 2, Rating: 1500},
+		{ID: 3, Rating: 1500},
+	})
+	if deltas[1] != 16 || deltas[2] != -8 || deltas[3] != -8 {
+		t.Fatalf("unexpected deltas %v", deltas)
+	}
+
+	deltas = Update([]Seat{
+		{ID: 1, Rating: 1500, Landlord: true},
+		{ID: 2, Rating: 1500, Winner: true},
+		{ID: 3, Rating: 1500, Winner: true},
+	})
+	if deltas[1] != -16 || deltas[2] != 8 || deltas[3] != 8 {
+		t.Fatalf("unexpected deltas %v", deltas)
+	}
+
+	// 六人局地主以一敌五，赢下来的分数应当比三人局更多
+	seats := []Seat{{ID: 1, Rating: 1500, Landlord: true, Winner: true}}
+	for i := int64(2); i <= 6; i++ {
+		seats = append(seats, Seat{ID: i, Rating: 1500})
+	}
+	deltas = Update(seats)
+	if deltas[1] <= 16 || deltas[1] > K {
+		t.Fatalf("unexpected landlord delta %d", deltas[1])
+	}
+
+	// 强者赢弱者得分少
+	deltas = Update([]Seat{{ID: 1, Rating: 1800, Winner: true}, {ID: 2, Rating: 1400}})
+	if deltas[1] <= 0 || deltas[1] >= 16 || deltas[1]+deltas[2] != 0 {
+		t.Fatalf("unexpected deltas %v", deltas)
+	}
+
+	// 有机器人参与的对局不计积分
+	deltas = Update([]Seat{{ID: 1, Rating: 1500, Winner: true}, {ID: -1, Rating: 1500, Robot: true}})
+	if len(deltas) != 0 {
+		t.Fatalf("expected no deltas against robots, got %v", deltas)
+	}
+}
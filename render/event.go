@@ -0,0 +1,231 @@
+package render
+
+import (
+	"fmt"
+	"github.com/ratel-online/core/log"
+	"github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/util/strings"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	stringx "strings"
+	"time"
+)
+
+type OptionsEvent struct {
+	Data
+	Options []model.Option `json:"options"`
+}
+
+type RoomListEvent struct {
+	Data
+	Rooms []model.Room `json:"rooms"`
+}
+
+type RoomEvent struct {
+	Data
+	Room   model.Room   `json:"room"`
+	Player model.Player `json:"player"`
+}
+
+// HandEvent tells a player its own pokers, nobody else gets them.
+type HandEvent struct {
+	Data
+	Pokers model.Pokers `json:"pokers"`
+}
+
+// HandsEvent carries the hands of everyone after they changed, every player only gets its own hand.
+type HandsEvent struct {
+	Data
+	Hands map[int64]model.Pokers `json:"hands"`
+}
+
+func (e HandsEvent) For(playerId int64) Event {
+	return HandEvent{Data: e.Data, Pokers: e.Hands[playerId]}
+}
+
+// DealEvent starts a hand, every player only gets its own hand and the first universal,
+// the pocket and the last universal are revealed with the landlord, the removed pokers of the duel with the settlement.
+type DealEvent struct {
+	Data
+	Hands      map[int64]model.Pokers `json:"hands"`
+	Pocket     model.Pokers           `json:"pocket"`
+	Removed    model.Pokers           `json:"removed,omitempty"`
+	Universals []int                  `json:"universals"`
+}
+
+func (e DealEvent) For(playerId int64) Event {
+	masked := DealEvent{Data: e.Data, Hands: map[int64]model.Pokers{}}
+	if pokers, ok := e.Hands[playerId]; ok {
+		masked.Hands[playerId] = pokers
+	}
+	if len(e.Universals) > 0 {
+		masked.Universals = e.Universals[:1]
+	}
+	return masked
+}
+
+type RobPromptEvent struct {
+	Data
+	Player   model.Player `json:"player"`
+	Deadline time.Time    `json:"deadline"`
+}
+
+type LandlordEvent struct {
+	Data
+	Player     model.Player `json:"player"`
+	Pocket     model.Pokers `json:"pocket"`
+	Universals []int        `json:"universals"`
+}
+
+type TurnEvent struct {
+	Data
+	Player   model.Player `json:"player"`
+	Timeout  int          `json:"timeout"` // 剩余秒数
+	Deadline time.Time    `json:"deadline"`
+}
+
+type PlayEvent struct {
+	Data
+	Player model.Player `json:"player"`
+	Pokers model.Pokers `json:"pokers"`
+	Faces  *model.Faces `json:"faces"` // 牌型
+}
+
+// PassEvent tells who passed and the faces it did not beat.
+type PassEvent struct {
+	Data
+	Player model.Player `json:"player"`
+	Faces  *model.Faces `json:"faces"`
+}
+
+type SkillEvent struct {
+	Data
+	Player model.Player `json:"player"`
+	Skill  string       `json:"skill"`
+}
+
+// GameOverEvent ends a hand, the match id is the one to replay it.
+type GameOverEvent struct {
+	Data
+	Winner  model.Player `json:"winner"`
+	MatchID int64        `json:"matchId"`
+}
+
+type RobEvent struct {
+	Data
+	Player model.Player `json:"player"`
+	Rob    bool         `json:"rob"`
+}
+
+// BidEvent tells the score the player bid, 0 means it did not bid.
+type BidEvent struct {
+	Data
+	Player model.Player `json:"player"`
+	Score  int          `json:"score"`
+}
+
+// DoubleEvent tells how a player doubled after the landlord was chosen, a multiple of 1 means it did not,
+// the hand of an open landlord is shown to everyone.
+type DoubleEvent struct {
+	Data
+	Player   model.Player `json:"player"`
+	Multiple int          `json:"multiple"`
+	Open     bool         `json:"open"`
+	Pokers   model.Pokers `json:"pokers,omitempty"`
+}
+
+type SettleEvent struct {
+	Data
+	Base     int                    `json:"base"`
+	Multiple int                    `json:"multiple"`
+	Players  []database.MatchPlayer `json:"players"`
+}
+
+type ChatEvent struct {
+	Data
+	Player    model.Player `json:"player"`
+	Content   string       `json:"content"`
+	Spectator bool         `json:"spectator"`
+}
+
+func NewHandEvent(pokers model.Pokers, msg string) HandEvent {
+	return HandEvent{Data: NewData(consts.CodeHand, msg), Pokers: pokers}
+}
+
+func NewHandsEvent(hands map[int64]model.Pokers, msg string) HandsEvent {
+	return HandsEvent{Data: NewData(consts.CodeHand, msg), Hands: hands}
+}
+
+func NewDealEvent(hands map[int64]model.Pokers, pocket, removed model.Pokers, universals []int, msg string) DealEvent {
+	return DealEvent{Data: NewData(consts.CodeDeal, msg), Hands: hands, Pocket: pocket, Removed: removed, Universals: universals}
+}
+
+func NewRobPromptEvent(player *database.Player, timeout time.Duration, msg string) RobPromptEvent {
+	return RobPromptEvent{Data: NewData(consts.CodeRobPrompt, msg), Player: player.Model(), Deadline: time.Now().Add(timeout)}
+}
+
+func NewLandlordEvent(player *database.Player, pocket model.Pokers, universals []int, msg string) LandlordEvent {
+	return LandlordEvent{Data: NewData(consts.CodeLandlord, msg), Player: player.Model(), Pocket: pocket, Universals: universals}
+}
+
+func NewTurnEvent(player *database.Player, timeout time.Duration, msg string) TurnEvent {
+	return TurnEvent{Data: NewData(consts.CodeTurn, msg), Player: player.Model(), Timeout: int(timeout.Seconds()), Deadline: time.Now().Add(timeout)}
+}
+
+func NewPlayEvent(player *database.Player, pokers model.Pokers, faces *model.Faces, msg string) PlayEvent {
+	return PlayEvent{Data: NewData(consts.CodePlay, msg), Player: player.Model(), Pokers: pokers, Faces: faces}
+}
+
+func NewPassEvent(player *database.Player, faces *model.Faces, msg string) PassEvent {
+	return PassEvent{Data: NewData(consts.CodePass, msg), Player: player.Model(), Faces: faces}
+}
+
+func NewSkillEvent(player *database.Player, skill string, msg string) SkillEvent {
+	return SkillEvent{Data: NewData(consts.CodeSkill, msg), Player: player.Model(), Skill: skill}
+}
+
+func NewGameOverEvent(winner *database.Player, matchId int64, msg string) GameOverEvent {
+	return GameOverEvent{Data: NewData(consts.CodeGameOver, msg), Winner: winner.Model(), MatchID: matchId}
+}
+
+func NewRobEvent(player *database.Player, rob bool, msg string) RobEvent {
+	return RobEvent{Data: NewData(consts.CodeRob, msg), Player: player.Model(), Rob: rob}
+}
+
+func NewBidEvent(player *database.Player, score int, msg string) BidEvent {
+	return BidEvent{Data: NewData(consts.CodeBid, msg), Player: player.Model(), Score: score}
+}
+
+func NewDoubleEvent(player *database.Player, multiple int, pokers model.Pokers, msg string) DoubleEvent {
+	return DoubleEvent{Data: NewData(consts.CodeDouble, msg), Player: player.Model(), Multiple: multiple, Open: pokers != nil, Pokers: pokers}
+}
+
+func NewSettleEvent(match *database.Match, base int, msg string) SettleEvent {
+	return SettleEvent{Data: NewData(consts.CodeSettle, msg), Base: base, Multiple: match.Multiple, Players: match.Players}
+}
+
+// Chat broadcasts what the player said to its room.
+func Chat(player *database.Player, content string) {
+	log.Infof("chat msg, player %s[%d] %s say: %s\n", player.Name, player.ID, player.IP, stringx.TrimSpace(content))
+	content = strings.Desensitize(content)
+	Broadcast(player.RoomID, ChatEvent{
+		Data:    NewData(consts.CodeChat, fmt.Sprintf("%s say: %s\n", player.Name, content)),
+		Player:  player.Model(),
+		Content: content,
+	})
+}
+
+// SpectatorChat sends what the spectator said to the other spectators only, players never see it.
+func SpectatorChat(player *database.Player, content string) {
+	log.Infof("spectator chat msg, player %s[%d] %s say: %s\n", player.Name, player.ID, player.IP, stringx.TrimSpace(content))
+	content = strings.Desensitize(content)
+	event := ChatEvent{
+		Data:      NewData(consts.CodeChat, fmt.Sprintf("[spectator] %s say: %s\n", player.Name, content)),
+		Player:    player.Model(),
+		Content:   content,
+		Spectator: true,
+	}
+	database.ForeachSpectator(player.WatchID, func(spectator *database.Player) {
+		_ = Of(spectator).Render(spectator, event, true)
+	}, player.ID)
+}
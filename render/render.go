@@ -7,28 +7,28 @@ import (
 	"github.com/ratel-online/core/model"
 	"github.com/ratel-online/server/consts"
 	"github.com/ratel-online/server/database"
+	"strconv"
+	"strings"
 )
 
 func Welcome(player *database.Player) error {
-	return player.WriteObject(model.Data{
-		Code: constx.CodeWelcome,
-		Msg:  fmt.Sprintf("Hi %s, Welcome to ratel online! \n", player.Name),
-	})
+	return Send(player, NewData(constx.CodeWelcome, fmt.Sprintf("Hi %s, Welcome to ratel online! rules at https://github.com/ratel-online/server/blob/main/README.md\n", player.Name)))
 }
 
 func HomeOptions(player *database.Player) error {
+	options := []model.Option{
+		{ID: 1, Name: "Join"},
+		{ID: 2, Name: "New"},
+		{ID: 3, Name: "Quick match"},
+		{ID: 4, Name: "Replay"},
+	}
 	buf := bytes.Buffer{}
-	buf.WriteString("1.Join\n")
-	buf.WriteString("2.New\n")
-	return player.WriteObject(model.Options{
-		Data: model.Data{
-			Code: constx.CodeHomeOptions,
-			Msg:  buf.String(),
-		},
-		Options: []model.Option{
-			{ID: 1, Name: "Join"},
-			{ID: 2, Name: "New"},
-		},
+	for _, option := range options {
+		buf.WriteString(fmt.Sprintf("%d.%s\n", option.ID, option.Name))
+	}
+	return Send(player, OptionsEvent{
+		Data:    NewData(constx.CodeHomeOptions, buf.String()),
+		Options: options,
 	})
 }
 
@@ -36,34 +36,24 @@ func GameTypeOptions(player *database.Player) error {
 	buf := bytes.Buffer{}
 	buf.WriteString("Please select game type\n")
 	options := make([]model.Option, 0)
-	for _, id := range consts.GameTypesIds {
+	for _, id := range database.GameTypes() {
 		buf.WriteString(fmt.Sprintf("%d.%s\n", id, consts.GameTypes[id]))
 		options = append(options, model.Option{ID: id, Name: consts.GameTypes[id]})
 	}
-	return player.WriteObject(model.Options{
-		Data: model.Data{
-			Code: constx.CodeGameTypeOptions,
-			Msg:  buf.String(),
-		},
+	return Send(player, OptionsEvent{
+		Data:    NewData(constx.CodeGameTypeOptions, buf.String()),
 		Options: options,
 	})
 }
 
-func RoomList(player *database.Player) error {
-	buf := bytes.Buffer{}
-	buf.WriteString(fmt.Sprintf("%-10s%-10s%-10s%-10s\n", "ID", "Type", "Players", "State"))
-	for _, room := range database.GetRooms() {
-		buf.WriteString(fmt.Sprintf("%-10d%-10s%-10d%-10s\n", room.ID, consts.GameTypes[room.Type], room.Players, consts.RoomStates[room.State]))
-	}
+// RoomList shows the rooms, msg is the table terminal clients see.
+func RoomList(player *database.Player, rooms []*database.Room, msg string) error {
 	modelRooms := make([]model.Room, 0)
-	for _, room := range database.GetRooms() {
+	for _, room := range rooms {
 		modelRooms = append(modelRooms, room.Model())
 	}
-	return player.WriteObject(model.RoomList{
-		Data: model.Data{
-			Code: constx.CodeRoomList,
-			Msg:  buf.String(),
-		},
+	return Send(player, RoomListEvent{
+		Data:  NewData(constx.CodeRoomList, msg),
 		Rooms: modelRooms,
 	})
 }
@@ -87,45 +77,136 @@ func Error(player *database.Player, err error) error {
 }
 
 func Join(player *database.Player, room *database.Room) {
-	database.BroadcastObject(room.ID, model.RoomEvent{
-		Data: model.Data{
-			Code: constx.CodeRoomEventJoin,
-			Msg:  fmt.Sprintf("%s joined room! room current has %d players\n", player.Name, room.Players),
-		},
+	Broadcast(room.ID, RoomEvent{
+		Data:   NewData(constx.CodeRoomEventJoin, fmt.Sprintf("%s joined room! room current has %d players\n", player.Name, room.Players)),
 		Room:   room.Model(),
 		Player: player.Model(),
 	})
 }
 
 func Exit(player *database.Player, room *database.Room) {
-	database.BroadcastObject(room.ID, model.RoomEvent{
-		Data: model.Data{
-			Code: constx.CodeRoomEventExit,
-			Msg:  fmt.Sprintf("%s exited room! room current has %d players\n", player.Name, room.Players),
-		},
+	Broadcast(room.ID, RoomEvent{
+		Data:   NewData(constx.CodeRoomEventExit, fmt.Sprintf("%s exited room! room current has %d players\n", player.Name, room.Players)),
 		Room:   room.Model(),
 		Player: player.Model(),
 	})
 }
 
 func Offline(player *database.Player, room *database.Room) {
-	database.BroadcastObject(room.ID, model.RoomEvent{
-		Data: model.Data{
-			Code: constx.CodeRoomEventOffline,
-			Msg:  fmt.Sprintf("%s lost connection", player.Name),
-		},
+	Broadcast(room.ID, RoomEvent{
+		Data:   NewData(constx.CodeRoomEventOffline, fmt.Sprintf("%s lost connection\n", player.Name)),
 		Room:   room.Model(),
 		Player: player.Model(),
 	})
 }
 
 func OwnerChange(player *database.Player, room *database.Room) {
-	database.BroadcastObject(room.ID, model.RoomEvent{
-		Data: model.Data{
-			Code: constx.CodeRoomEventOwnerChange,
-			Msg:  fmt.Sprintf("%s become new owner\n", player.Name),
-		},
+	Broadcast(room.ID, RoomEvent{
+		Data:   NewData(constx.CodeRoomEventOwnerChange, fmt.Sprintf("%s become new owner\n", player.Name)),
 		Room:   room.Model(),
 		Player: player.Model(),
 	})
 }
+
+// Rank shows the ratings of the player in every game type and the place on each leaderboard.
+func Rank(player *database.Player) error {
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("Ratings of %s\n", player.Name))
+	buf.WriteString(fmt.Sprintf("%-10s%-10s%-10s\n", "Type", "Rating", "Rank"))
+	for _, id := range consts.GameTypesIds {
+		records, err := database.Leaderboard(id)
+		if err != nil {
+			return err
+		}
+		rank := "-"
+		for i, record := range records {
+			if record.ID == player.ID {
+				rank = fmt.Sprintf("#%d/%d", i+1, len(records))
+				break
+			}
+		}
+		buf.WriteString(fmt.Sprintf("%-10s%-10d%-10s\n", consts.GameTypes[id], database.GetRating(player.ID, id), rank))
+	}
+	return player.WriteString(buf.String())
+}
+
+// Profile shows the match history summary and the ratings of a player.
+func Profile(player *database.Player, nameOrId string) error {
+	profile, err := database.GetProfile(nameOrId)
+	if err != nil {
+		return err
+	}
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("Profile of %s, id: %d, score: %d\n", profile.Record.Name, profile.Record.ID, profile.Record.Score))
+	buf.WriteString(fmt.Sprintf("Games: %d, win rate: %s, max multiple: %d\n", profile.Games, rate(profile.Wins, profile.Games), profile.MaxMultiple))
+	buf.WriteString(fmt.Sprintf("Landlord: %d games, win rate: %s\n", profile.LandlordGames, rate(profile.LandlordWins, profile.LandlordGames)))
+	buf.WriteString(fmt.Sprintf("Peasant: %d games, win rate: %s\n", profile.PeasantGames, rate(profile.PeasantWins, profile.PeasantGames)))
+	buf.WriteString("Ratings: ")
+	for _, id := range consts.GameTypesIds {
+		buf.WriteString(fmt.Sprintf("%s %d ", consts.GameTypes[id], database.GetRating(profile.Record.ID, id)))
+	}
+	buf.WriteString("\n")
+	return player.WriteString(buf.String())
+}
+
+// Top shows the best rated players of the game type.
+func Top(player *database.Player, gameType int) error {
+	if _, ok := consts.GameTypes[gameType]; !ok {
+		return consts.ErrorsGameTypeInvalid
+	}
+	records, err := database.Leaderboard(gameType)
+	if err != nil {
+		return err
+	}
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("Top players of %s\n", consts.GameTypes[gameType]))
+	buf.WriteString(fmt.Sprintf("%-6s%-20s%-10s%-10s\n", "Rank", "Name", "ID", "Rating"))
+	for i, record := range records {
+		if i >= consts.TopPlayers {
+			break
+		}
+		buf.WriteString(fmt.Sprintf("%-6d%-20s%-10d%-10d\n", i+1, record.Name, record.ID, record.Ratings[gameType]))
+	}
+	return player.WriteString(buf.String())
+}
+
+// Query answers the rank, profile and top commands, it reports whether the signal was one of them.
+// Top defaults to the game type, the one of the room the player is in.
+func Query(player *database.Player, signal string, gameType int) bool {
+	tags := strings.Fields(signal)
+	if len(tags) == 0 {
+		return false
+	}
+	var err error
+	switch tags[0] {
+	case "rank":
+		err = Rank(player)
+	case "profile":
+		if len(tags) > 1 {
+			err = Profile(player, strings.Join(tags[1:], " "))
+		} else {
+			err = Profile(player, strconv.FormatInt(player.ID, 10))
+		}
+	case "top":
+		if len(tags) > 1 {
+			if gameType, err = strconv.Atoi(tags[1]); err != nil {
+				err = consts.ErrorsGameTypeInvalid
+				break
+			}
+		}
+		err = Top(player, gameType)
+	default:
+		return false
+	}
+	if err != nil {
+		_ = player.WriteError(err)
+	}
+	return true
+}
+
+func rate(wins, games int) string {
+	if games == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", float64(wins)*100/float64(games))
+}
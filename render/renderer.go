@@ -0,0 +1,76 @@
+package render
+
+import (
+	"github.com/ratel-online/core/model"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+)
+
+// Event is what the server tells its clients, terminal clients only get the message of it.
+type Event interface {
+	Message() string
+}
+
+// Data is embedded in every event, Msg is the text terminal clients see.
+type Data struct {
+	model.Data
+}
+
+func NewData(code int, msg string) Data {
+	return Data{Data: model.Data{Code: code, Msg: msg}}
+}
+
+func (d Data) Message() string {
+	return d.Msg
+}
+
+// Renderer writes events in the protocol of a connection, negotiated at login.
+type Renderer interface {
+	// Render writes the event to the player, broadcast marks it as sent to the whole room.
+	Render(player *database.Player, event Event, broadcast bool) error
+}
+
+// Text renders events as the terminal text they always were.
+type Text struct{}
+
+func (Text) Render(player *database.Player, event Event, broadcast bool) error {
+	if event.Message() == "" {
+		return nil
+	}
+	if broadcast {
+		return player.WriteString(">> " + event.Message())
+	}
+	return player.WriteString(event.Message())
+}
+
+// JSON renders events as typed objects for GUI clients, the text is kept in the msg field.
+type JSON struct{}
+
+func (JSON) Render(player *database.Player, event Event, broadcast bool) error {
+	return player.WriteObject(event)
+}
+
+// Of returns the renderer of the protocol the player negotiated.
+func Of(player *database.Player) Renderer {
+	if player.Mode() == consts.ProtocolJSON {
+		return JSON{}
+	}
+	return Text{}
+}
+
+// Send writes the event to the player.
+func Send(player *database.Player, event Event) error {
+	return Of(player).Render(player, event, false)
+}
+
+// Broadcast writes the event to the players and spectators of the room, private events are
+// narrowed down to what each of them may see.
+func Broadcast(roomId int64, event Event, exclude ...int64) {
+	database.ForeachListener(roomId, func(player *database.Player) {
+		if private, ok := event.(Private); ok {
+			_ = Of(player).Render(player, private.For(player.ID), true)
+			return
+		}
+		_ = Of(player).Render(player, event, true)
+	}, exclude...)
+}
@@ -0,0 +1,62 @@
+package render
+
+import (
+	"encoding/json"
+	"github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/network"
+	"github.com/ratel-online/core/protocol"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"testing"
+)
+
+type recorder struct {
+	packets []protocol.Packet
+}
+
+func (r *recorder) Read() (*protocol.Packet, error) { return nil, nil }
+func (r *recorder) Write(msg protocol.Packet) error { r.packets = append(r.packets, msg); return nil }
+func (r *recorder) Close() error                    { return nil }
+func (r *recorder) IP() string                      { return "127.0.0.1" }
+
+func newPlayer(mode int) (*database.Player, *recorder) {
+	r := &recorder{}
+	player := &database.Player{ID: 1, Name: "nico"}
+	player.Conn(network.Wrapper(r), mode)
+	return player, r
+}
+
+func TestRender(t *testing.T) {
+	event := NewHandEvent(model.Pokers{{Key: 3, Val: 1, Desc: "3"}}, "Your pokers: 3\n")
+
+	player, r := newPlayer(consts.ProtocolText)
+	if err := Send(player, event); err != nil {
+		t.Fatal(err)
+	}
+	if string(r.packets[0].Body) != "Your pokers: 3\n" {
+		t.Fatalf("unexpected text %q", r.packets[0].Body)
+	}
+
+	player, r = newPlayer(consts.ProtocolJSON)
+	if err := Send(player, event); err != nil {
+		t.Fatal(err)
+	}
+	hand := HandEvent{}
+	if err := json.Unmarshal(r.packets[0].Body, &hand); err != nil {
+		t.Fatal(err)
+	}
+	if hand.Code != consts.CodeHand || hand.Msg != "Your pokers: 3\n" || len(hand.Pokers) != 1 || hand.Pokers[0].Key != 3 {
+		t.Fatalf("unexpected event %s", r.packets[0].Body)
+	}
+
+	// JSON连接上的普通文本和错误同样以JSON发送
+	_ = player.WriteString("hello\n")
+	_ = player.WriteError(consts.ErrorsInputInvalid)
+	data := model.Data{}
+	if err := json.Unmarshal(r.packets[1].Body, &data); err != nil || data.Code != consts.CodeMessage || data.Msg != "hello\n" {
+		t.Fatalf("unexpected message %s %v", r.packets[1].Body, err)
+	}
+	if err := json.Unmarshal(r.packets[2].Body, &data); err != nil || data.Code != consts.CodeError {
+		t.Fatalf("unexpected error %s %v", r.packets[2].Body, err)
+	}
+}
@@ -0,0 +1,66 @@
+package render
+
+import (
+	"github.com/ratel-online/server/database"
+	"sync"
+)
+
+// Subscriber consumes the events of a room, it gets every event as is, private parts included.
+type Subscriber func(event Event)
+
+// Private is implemented by events carrying hands, every listener only gets its own part.
+type Private interface {
+	For(playerId int64) Event
+}
+
+type subscription struct {
+	id         int64
+	subscriber Subscriber
+}
+
+var (
+	subscriptionsLock sync.Mutex
+	subscriptions     = map[int64][]subscription{}
+	subscriptionIds   int64
+)
+
+func init() {
+	// 房间删除时一并丢弃订阅，游戏可能没有结束事件
+	database.OnRoomDeleted(func(roomId int64, game *database.Game) {
+		subscriptionsLock.Lock()
+		defer subscriptionsLock.Unlock()
+		delete(subscriptions, roomId)
+	})
+}
+
+// Subscribe adds a subscriber to the room until the returned cancel is called
+// or the room is deleted.
+func Subscribe(roomId int64, subscriber Subscriber) (cancel func()) {
+	subscriptionsLock.Lock()
+	defer subscriptionsLock.Unlock()
+	subscriptionIds++
+	id := subscriptionIds
+	subscriptions[roomId] = append(subscriptions[roomId], subscription{id: id, subscriber: subscriber})
+	return func() {
+		subscriptionsLock.Lock()
+		defer subscriptionsLock.Unlock()
+		list := subscriptions[roomId]
+		for i := range list {
+			if list[i].id == id {
+				subscriptions[roomId] = append(list[:i:i], list[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// Publish broadcasts the event to the room and hands it to the subscribers of the room in order.
+func Publish(roomId int64, event Event, exclude ...int64) {
+	Broadcast(roomId, event, exclude...)
+	subscriptionsLock.Lock()
+	list := append([]subscription{}, subscriptions[roomId]...)
+	subscriptionsLock.Unlock()
+	for _, s := range list {
+		s.subscriber(event)
+	}
+}
@@ -0,0 +1,70 @@
+package render
+
+import (
+	"encoding/json"
+	"github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/network"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"testing"
+)
+
+func TestPublish(t *testing.T) {
+	recorders := map[int64]*recorder{}
+	for _, id := range []int64{11, 12} {
+		r := &recorder{}
+		_, _, err := database.Login(network.Wrapper(r), &model.AuthInfo{ID: id, Name: "nico"}, consts.ProtocolJSON)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recorders[id] = r
+	}
+	room := database.CreateRoom(11, "", 3)
+	for id := range recorders {
+		if err := database.JoinRoom(room.ID, id, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	events := make([]Event, 0)
+	cancel := Subscribe(room.ID, func(event Event) {
+		events = append(events, event)
+	})
+	hands := map[int64]model.Pokers{
+		11: {{Key: 3, Val: 1, Desc: "3"}},
+		12: {{Key: 4, Val: 2, Desc: "4"}},
+	}
+	Publish(room.ID, NewDealEvent(hands, model.Pokers{{Key: 5, Val: 3, Desc: "5"}}, model.Pokers{{Key: 9, Val: 7, Desc: "9"}}, []int{6, 7}, ""))
+	cancel()
+	Publish(room.ID, NewPassEvent(database.GetPlayer(11), nil, "nico passed\n"))
+
+	// 订阅者收到完整事件，玩家只能看到自己的手牌
+	if len(events) != 1 || len(events[0].(DealEvent).Hands) != 2 {
+		t.Fatalf("unexpected events %v", events)
+	}
+	for id, r := range recorders {
+		deal := DealEvent{}
+		if err := json.Unmarshal(r.packets[len(r.packets)-2].Body, &deal); err != nil {
+			t.Fatal(err)
+		}
+		if deal.Code != consts.CodeDeal || len(deal.Hands) != 1 || deal.Hands[id][0].Key != hands[id][0].Key {
+			t.Fatalf("unexpected deal for %d: %s", id, r.packets[len(r.packets)-2].Body)
+		}
+		if len(deal.Pocket) != 0 || len(deal.Removed) != 0 || len(deal.Universals) != 1 {
+			t.Fatalf("pocket, removed pokers or universals leaked to %d: %s", id, r.packets[len(r.packets)-2].Body)
+		}
+	}
+}
+
+func TestSubscribeDeletedRoom(t *testing.T) {
+	room := database.CreateRoom(11, "", 3)
+	Subscribe(room.ID, func(event Event) {})
+	if err := database.DissolveRoom(room.ID); err != nil {
+		t.Fatal(err)
+	}
+	subscriptionsLock.Lock()
+	defer subscriptionsLock.Unlock()
+	if _, ok := subscriptions[room.ID]; ok {
+		t.Fatal("expected the subscriptions of the deleted room to be dropped")
+	}
+}
@@ -0,0 +1,83 @@
+package robot
+
+import (
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/server/database"
+)
+
+// Greedy always plays the smallest hand which beats the last one.
+var Greedy Strategy = greedy{}
+
+type greedy struct{}
+
+func (greedy) Rob(game *database.Game, playerId int64) bool {
+	h := newHand(game, game.Pokers[playerId])
+	strength := h.count(14) + h.count(15) + h.count(2)
+	for _, k := range h.keys {
+		if h.count(k) >= 4 {
+			strength += 2
+		}
+	}
+	return strength >= 4
+}
+
+func (greedy) Play(game *database.Game, playerId int64, master bool) string {
+	h := newHand(game, game.Pokers[playerId])
+	lastFaces := game.LastFaces
+	if master {
+		lastFaces = nil
+	}
+	if _, ok := beats(game, h.parse(h.all()), lastFaces); ok {
+		return answer(h.all())
+	}
+	if !master && game.IsTeammate(playerId, game.LastPlayer) {
+		return "p"
+	}
+	var best, bomb *candidate
+	for _, c := range h.candidates() {
+		c := c
+		faces, ok := beats(game, []modelx.Faces{c.faces}, lastFaces)
+		if !ok {
+			continue
+		}
+		c.faces = faces
+		if isBomb(c.faces) {
+			if bomb == nil || c.faces.Score < bomb.faces.Score {
+				bomb = &c
+			}
+			continue
+		}
+		if best == nil || better(game, c, *best, master) {
+			best = &c
+		}
+	}
+	if best == nil {
+		best = bomb
+	}
+	if best == nil {
+		return "p"
+	}
+	return answer(best.keys)
+}
+
+// better prefers to get rid of the smallest poker as master, and the smallest hand otherwise.
+func better(game *database.Game, c, best candidate, master bool) bool {
+	if master {
+		cv, bv := lowest(game, c.keys), lowest(game, best.keys)
+		if cv != bv {
+			return cv < bv
+		}
+		return len(c.keys) > len(best.keys)
+	}
+	return c.faces.Score < best.faces.Score
+}
+
+func lowest(game *database.Game, keys []int) int {
+	min := game.Rules.Value(keys[0])
+	for _, k := range keys {
+		if v := game.Rules.Value(k); v < min {
+			min = v
+		}
+	}
+	return min
+}
@@ -0,0 +1,93 @@
+package robot
+
+import (
+	constx "github.com/ratel-online/core/consts"
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/render"
+	"sync"
+)
+
+// kind is the shape of a play, plays can only beat plays of the same kind, bombs aside.
+type kind struct {
+	typ  constx.FacesType
+	size int
+}
+
+// memory remembers the plays every player could not beat while the game goes on.
+type memory struct {
+	sync.Mutex
+	passed map[int64]map[kind]int64
+}
+
+var (
+	memoriesLock sync.Mutex
+	memories     = map[*database.Game]*memory{}
+)
+
+func init() {
+	// 解散、中止或过期的游戏没有结束事件，随房间一起清理
+	database.OnRoomDeleted(func(roomId int64, game *database.Game) {
+		if game != nil {
+			memoriesLock.Lock()
+			delete(memories, game)
+			memoriesLock.Unlock()
+		}
+	})
+}
+
+// Observe returns the subscriber feeding the memory of the robots playing the game,
+// it has to be subscribed to the events of the room before the deal.
+func Observe(game *database.Game) render.Subscriber {
+	m := &memory{passed: map[int64]map[kind]int64{}}
+	memoriesLock.Lock()
+	memories[game] = m
+	memoriesLock.Unlock()
+	return func(event render.Event) {
+		switch e := event.(type) {
+		case render.DealEvent:
+			m.Lock()
+			m.passed = map[int64]map[kind]int64{}
+			m.Unlock()
+		case render.PassEvent:
+			if e.Faces == nil || game.IsTeammate(e.Player.ID, game.LastPlayer) {
+				return
+			}
+			m.pass(e.Player.ID, *e.Faces)
+		case render.GameOverEvent:
+			memoriesLock.Lock()
+			delete(memories, game)
+			memoriesLock.Unlock()
+		}
+	}
+}
+
+func memoryOf(game *database.Game) *memory {
+	memoriesLock.Lock()
+	defer memoriesLock.Unlock()
+	return memories[game]
+}
+
+// pass keeps the lowest play of each kind the player passed on.
+func (m *memory) pass(playerId int64, faces modelx.Faces) {
+	m.Lock()
+	defer m.Unlock()
+	k := kind{typ: faces.Type, size: len(faces.Keys)}
+	if m.passed[playerId] == nil {
+		m.passed[playerId] = map[kind]int64{}
+	}
+	if score, ok := m.passed[playerId][k]; !ok || faces.Score < score {
+		m.passed[playerId][k] = faces.Score
+	}
+}
+
+// unbeatable reports whether the player passed on a lower play of the same kind before.
+func (m *memory) unbeatable(playerId int64, faces modelx.Faces) bool {
+	if m == nil || isBomb(faces) {
+		return false
+	}
+	m.Lock()
+	defer m.Unlock()
+	score, ok := m.passed[playerId][kind{typ: faces.Type, size: len(faces.Keys)}]
+	return ok && faces.Score >= score
+}
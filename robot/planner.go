@@ -0,0 +1,253 @@
+package robot
+
+import (
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/util/arrays"
+	"github.com/ratel-online/server/database"
+	"sort"
+)
+
+// Planner decomposes the hand into bombs, planes, straights and sets before playing,
+// it keeps bombs for the end game and tries to empty the hand in as few turns as possible.
+var Planner Strategy = planner{}
+
+type planner struct{}
+
+func (planner) Rob(game *database.Game, playerId int64) bool {
+	h := newHand(game, game.Pokers[playerId])
+	strength := h.count(14) + h.count(15) + h.count(2)
+	groups := decompose(h)
+	for _, g := range groups {
+		if isBomb(g.faces) {
+			strength += 2
+		}
+	}
+	return strength >= 4 || (strength >= 3 && len(groups) <= 7)
+}
+
+func (planner) Play(game *database.Game, playerId int64, master bool) string {
+	h := newHand(game, game.Pokers[playerId])
+	lastFaces := game.LastFaces
+	if master {
+		lastFaces = nil
+	}
+	if _, ok := beats(game, h.parse(h.all()), lastFaces); ok {
+		return answer(h.all())
+	}
+	groups := decompose(h)
+	if master {
+		if c := lead(game, playerId, groups); len(c.keys) > 0 {
+			return answer(c.keys)
+		}
+		return "p"
+	}
+	if game.IsTeammate(playerId, game.LastPlayer) {
+		return "p"
+	}
+	var best *candidate
+	for _, g := range groups {
+		g := g
+		if isBomb(g.faces) {
+			continue
+		}
+		if _, ok := beats(game, []modelx.Faces{g.faces}, lastFaces); ok && (best == nil || g.faces.Score < best.faces.Score) {
+			best = &g
+		}
+	}
+	if best != nil {
+		return answer(best.keys)
+	}
+	danger := len(game.Pokers[game.LastPlayer]) <= 5
+	if danger {
+		if ans := Greedy.Play(game, playerId, false); ans != "p" {
+			return ans
+		}
+	}
+	if danger || len(groups) <= 2 {
+		var bomb *candidate
+		for _, g := range groups {
+			g := g
+			if !isBomb(g.faces) {
+				continue
+			}
+			if _, ok := beats(game, []modelx.Faces{g.faces}, lastFaces); ok && (bomb == nil || g.faces.Score < bomb.faces.Score) {
+				bomb = &g
+			}
+		}
+		if bomb != nil {
+			return answer(bomb.keys)
+		}
+	}
+	return "p"
+}
+
+// lead chooses the group to play as master: the one with the smallest poker, preferring long
+// groups, bombs only when nothing else is left, and no singles when the next opponent is about to win.
+// Groups of a kind the next opponent could not beat before go first.
+func lead(game *database.Game, playerId int64, groups []candidate) candidate {
+	if len(groups) == 0 {
+		return candidate{}
+	}
+	next := game.NextPlayer(playerId)
+	opponent := !game.IsTeammate(playerId, next)
+	alarm := opponent && len(game.Pokers[next]) == 1
+	m := memoryOf(game)
+	sort.SliceStable(groups, func(i, j int) bool {
+		bi, bj := isBomb(groups[i].faces), isBomb(groups[j].faces)
+		if bi != bj {
+			return bj
+		}
+		if alarm {
+			si, sj := len(groups[i].keys) == 1, len(groups[j].keys) == 1
+			if si != sj {
+				return sj
+			}
+		}
+		if opponent {
+			ui, uj := m.unbeatable(next, groups[i].faces), m.unbeatable(next, groups[j].faces)
+			if ui != uj {
+				return ui
+			}
+		}
+		li, lj := lowest(game, groups[i].keys), lowest(game, groups[j].keys)
+		if li != lj {
+			return li < lj
+		}
+		return len(groups[i].keys) > len(groups[j].keys)
+	})
+	if alarm && len(groups[0].keys) == 1 {
+		highest := groups[0]
+		for _, g := range groups {
+			if len(g.keys) == 1 && !isBomb(g.faces) && g.faces.Score > highest.faces.Score {
+				highest = g
+			}
+		}
+		return highest
+	}
+	return groups[0]
+}
+
+// decompose splits the hand into groups, bombs and the rocket first, then planes, straights,
+// pair straights and sets, the remaining singles and pairs are attached to triples as kickers.
+func decompose(h hand) []candidate {
+	counts := map[int]int{}
+	for _, k := range h.keys {
+		counts[k] = h.count(k)
+	}
+	take := func(keys []int) {
+		for _, k := range keys {
+			counts[k]--
+		}
+	}
+	groups := make([][]int, 0)
+	if counts[14] > 0 && counts[15] > 0 {
+		jokers := arrays.AppendN(arrays.AppendN(nil, 14, counts[14]), 15, counts[15])
+		groups = append(groups, jokers)
+		take(jokers)
+	}
+	for _, k := range h.keys {
+		if counts[k] >= 4 {
+			bomb := arrays.AppendN(nil, k, counts[k])
+			groups = append(groups, bomb)
+			take(bomb)
+		}
+	}
+	for _, chain := range []struct{ width, min int }{{3, 2}, {1, 5}, {2, 3}} {
+		for {
+			longest := longestChain(h, counts, chain.width, chain.min)
+			if longest == nil {
+				break
+			}
+			groups = append(groups, longest)
+			take(longest)
+		}
+	}
+	for _, k := range h.keys {
+		if counts[k] > 0 {
+			set := arrays.AppendN(nil, k, counts[k])
+			groups = append(groups, set)
+			take(set)
+		}
+	}
+	groups = attachKickers(h, groups)
+	list := make([]candidate, 0, len(groups))
+	for _, keys := range groups {
+		if facesArr := h.parse(keys); len(facesArr) > 0 {
+			list = append(list, candidate{keys: keys, faces: facesArr[0]})
+		}
+	}
+	return list
+}
+
+// longestChain returns the longest run of keys having width pokers each in the counts.
+func longestChain(h hand, counts map[int]int, width, min int) []int {
+	var best, run []int
+	for _, k := range h.keys {
+		if counts[k] < width || k == 2 || k == 14 || k == 15 || (len(run) > 0 && h.game.Rules.Value(k) != h.game.Rules.Value(run[len(run)-1])+1) {
+			if len(run) > len(best) {
+				best = run
+			}
+			run = nil
+		}
+		if counts[k] >= width && k != 2 && k != 14 && k != 15 {
+			run = append(run, k)
+		}
+	}
+	if len(run) > len(best) {
+		best = run
+	}
+	if len(best) < min {
+		return nil
+	}
+	keys := make([]int, 0)
+	for _, k := range best {
+		keys = arrays.AppendN(keys, k, width)
+	}
+	return keys
+}
+
+// attachKickers gives the smallest singles, or else pairs, to triples and planes.
+func attachKickers(h hand, groups [][]int) [][]int {
+	singles, pairs, rest := make([][]int, 0), make([][]int, 0), make([][]int, 0)
+	for _, g := range groups {
+		if len(g) == 1 && g[0] != 2 && g[0] < 14 {
+			singles = append(singles, g)
+		} else if len(g) == 2 && g[0] == g[1] && g[0] != 2 && g[0] < 14 {
+			pairs = append(pairs, g)
+		} else {
+			rest = append(rest, g)
+		}
+	}
+	for i, g := range rest {
+		triples := len(g) / 3
+		if len(g)%3 != 0 || !isTriples(g) {
+			continue
+		}
+		if len(singles) >= triples {
+			for _, s := range singles[:triples] {
+				rest[i] = append(rest[i], s...)
+			}
+			singles = singles[triples:]
+		} else if len(pairs) >= triples {
+			for _, p := range pairs[:triples] {
+				rest[i] = append(rest[i], p...)
+			}
+			pairs = pairs[triples:]
+		}
+	}
+	rest = append(rest, pairs...)
+	return append(rest, singles...)
+}
+
+func isTriples(keys []int) bool {
+	counts := map[int]int{}
+	for _, k := range keys {
+		counts[k]++
+	}
+	for _, c := range counts {
+		if c != 3 {
+			return false
+		}
+	}
+	return true
+}
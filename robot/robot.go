@@ -0,0 +1,224 @@
+package robot
+
+import (
+	constx "github.com/ratel-online/core/consts"
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/util/arrays"
+	"github.com/ratel-online/core/util/poker"
+	"github.com/ratel-online/server/database"
+	"sort"
+	"strings"
+)
+
+// Strategy decides what a server controlled seat does on its turn.
+type Strategy interface {
+	// Rob returns whether the player wants to become landlord.
+	Rob(game *database.Game, playerId int64) bool
+	// Play returns the answer as if the player typed it, "p" means pass.
+	Play(game *database.Game, playerId int64, master bool) string
+}
+
+// candidate is a combination of keys that can be played from a hand.
+type candidate struct {
+	keys  []int
+	faces modelx.Faces
+}
+
+// hand groups the pokers of a player by key.
+type hand struct {
+	game   *database.Game
+	pokers map[int]modelx.Pokers
+	keys   []int
+}
+
+func newHand(game *database.Game, pokers modelx.Pokers) hand {
+	h := hand{game: game, pokers: map[int]modelx.Pokers{}}
+	for _, p := range pokers {
+		if len(h.pokers[p.Key]) == 0 {
+			h.keys = append(h.keys, p.Key)
+		}
+		h.pokers[p.Key] = append(h.pokers[p.Key], p)
+	}
+	sort.Slice(h.keys, func(i, j int) bool {
+		return game.Rules.Value(h.keys[i]) < game.Rules.Value(h.keys[j])
+	})
+	return h
+}
+
+func (h hand) count(key int) int {
+	return len(h.pokers[key])
+}
+
+func (h hand) size() int {
+	size := 0
+	for _, pokers := range h.pokers {
+		size += len(pokers)
+	}
+	return size
+}
+
+func (h hand) all() []int {
+	keys := make([]int, 0)
+	for _, k := range h.keys {
+		keys = arrays.AppendN(keys, k, h.count(k))
+	}
+	return keys
+}
+
+// parse returns the faces of the keys, the pokers are picked the same way as the game state does.
+func (h hand) parse(keys []int) []modelx.Faces {
+	used := map[int]int{}
+	pokers := make(modelx.Pokers, 0, len(keys))
+	for _, k := range keys {
+		if used[k] >= h.count(k) {
+			return nil
+		}
+		pokers = append(pokers, h.pokers[k][used[k]])
+		used[k]++
+	}
+	return poker.ParseFaces(pokers, h.game.Rules)
+}
+
+// chains returns every run of consecutive keys having at least width pokers each.
+func (h hand) chains(width, min int) [][]int {
+	list := make([][]int, 0)
+	run := make([]int, 0)
+	flush := func() {
+		for i := 0; i < len(run); i++ {
+			for j := i + min; j <= len(run); j++ {
+				list = append(list, append([]int{}, run[i:j]...))
+			}
+		}
+		run = run[:0]
+	}
+	for _, k := range h.keys {
+		if h.count(k) < width {
+			flush()
+			continue
+		}
+		if len(run) > 0 && h.game.Rules.Value(k) != h.game.Rules.Value(run[len(run)-1])+1 {
+			flush()
+		}
+		run = append(run, k)
+	}
+	flush()
+	return list
+}
+
+// candidates lists the valid combinations which can be played from the hand.
+func (h hand) candidates() []candidate {
+	combos := make([][]int, 0)
+	jokers := make([]int, 0)
+	for _, k := range h.keys {
+		c := h.count(k)
+		for n := 1; n <= c; n++ {
+			combos = append(combos, arrays.AppendN(nil, k, n))
+		}
+		if k == 14 || k == 15 {
+			jokers = arrays.AppendN(jokers, k, c)
+		}
+		if c >= 3 {
+			for _, o := range h.keys {
+				if o == k {
+					continue
+				}
+				combos = append(combos, arrays.AppendN(arrays.AppendN(nil, k, 3), o, 1))
+				if h.count(o) >= 2 {
+					combos = append(combos, arrays.AppendN(arrays.AppendN(nil, k, 3), o, 2))
+				}
+			}
+		}
+	}
+	if len(jokers) > 1 {
+		combos = append(combos, jokers)
+	}
+	// 连对从两对开始枚举，规则不允许的组合在解析时被过滤
+	for width, min := range []int{1: 5, 2: 2, 3: 2} {
+		if min == 0 {
+			continue
+		}
+		for _, chain := range h.chains(width, min) {
+			keys := make([]int, 0)
+			for _, k := range chain {
+				keys = arrays.AppendN(keys, k, width)
+			}
+			combos = append(combos, keys)
+			if width == 3 {
+				combos = append(combos, h.wings(chain, keys, 1), h.wings(chain, keys, 2))
+			}
+		}
+	}
+	list := make([]candidate, 0)
+	for _, keys := range combos {
+		if len(keys) == 0 {
+			continue
+		}
+		if facesArr := h.parse(keys); len(facesArr) > 0 {
+			list = append(list, candidate{keys: keys, faces: facesArr[0]})
+		}
+	}
+	return list
+}
+
+// wings attaches the smallest singles or pairs outside of the chain to a plane.
+func (h hand) wings(chain []int, keys []int, width int) []int {
+	inChain := map[int]bool{}
+	for _, k := range chain {
+		inChain[k] = true
+	}
+	need := len(chain)
+	keys = append([]int{}, keys...)
+	for _, k := range h.keys {
+		if need == 0 {
+			break
+		}
+		if inChain[k] || h.count(k) < width || k == 14 || k == 15 {
+			continue
+		}
+		keys = arrays.AppendN(keys, k, width)
+		need--
+	}
+	if need > 0 {
+		return nil
+	}
+	return keys
+}
+
+// beats returns the faces of the keys if they can be played over the last faces.
+func beats(game *database.Game, facesArr []modelx.Faces, lastFaces *modelx.Faces) (modelx.Faces, bool) {
+	if lastFaces != nil && game.IsMax(*lastFaces) {
+		return modelx.Faces{}, false
+	}
+	for _, faces := range facesArr {
+		if lastFaces == nil || game.IsMax(faces) || faces.Compare(*lastFaces) {
+			return faces, true
+		}
+	}
+	return modelx.Faces{}, false
+}
+
+// CanBeat reports whether the player has anything to play over the last faces.
+func CanBeat(game *database.Game, playerId int64) bool {
+	if game.LastFaces == nil {
+		return true
+	}
+	h := newHand(game, game.Pokers[playerId])
+	for _, c := range h.candidates() {
+		if _, ok := beats(game, []modelx.Faces{c.faces}, game.LastFaces); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isBomb(faces modelx.Faces) bool {
+	return faces.Type == constx.FacesBomb
+}
+
+func answer(keys []int) string {
+	buf := strings.Builder{}
+	for _, k := range keys {
+		buf.WriteString(poker.GetAlias(k))
+	}
+	return buf.String()
+}
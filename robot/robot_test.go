@@ -0,0 +1,127 @@
+package robot
+
+import (
+	"fmt"
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/util/poker"
+	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/rule"
+	"testing"
+)
+
+// newGame deals the keys to the players 1, 2 and 3 of a landlord game, 1 is the landlord.
+func newGame(hands ...[]int) *database.Game {
+	game := &database.Game{
+		Players:    []int64{1, 2, 3},
+		Groups:     map[int64]int{1: 1},
+		Pokers:     map[int64]modelx.Pokers{},
+		Decks:      1,
+		Rules:      rule.LandlordRules,
+		Properties: map[string]bool{},
+	}
+	for i, keys := range hands {
+		game.Pokers[int64(i+1)] = pokersOf(keys...)
+	}
+	return game
+}
+
+func pokersOf(keys ...int) modelx.Pokers {
+	pokers := poker.GetPokers(keys...)
+	for i := range pokers {
+		pokers[i].Val = rule.LandlordRules.Value(pokers[i].Key)
+	}
+	return pokers
+}
+
+func TestCandidates(t *testing.T) {
+	tests := []struct {
+		hand    []int
+		present [][]int
+		absent  [][]int
+	}{
+		{hand: []int{3, 3, 3, 4}, present: [][]int{{3}, {3, 3}, {3, 3, 3}, {3, 3, 3, 4}, {4}}, absent: [][]int{{3, 4}}},
+		{hand: []int{3, 4, 5, 6, 7}, present: [][]int{{3, 4, 5, 6, 7}}, absent: [][]int{{3, 4, 5, 6}}},
+		// 连对至少三对
+		{hand: []int{3, 3, 4, 4, 5, 5}, present: [][]int{{3, 3, 4, 4, 5, 5}}, absent: [][]int{{3, 3, 4, 4}}},
+		{hand: []int{3, 3, 3, 4, 4, 4, 5, 6}, present: [][]int{{3, 3, 3, 4, 4, 4, 5, 6}, {3, 3, 3, 4, 4, 4}}},
+		{hand: []int{5, 5, 5, 5, 14, 15}, present: [][]int{{5, 5, 5, 5}, {14, 15}}},
+		{hand: []int{}},
+	}
+	for _, tt := range tests {
+		game := newGame(tt.hand)
+		found := map[string]bool{}
+		for _, c := range newHand(game, game.Pokers[1]).candidates() {
+			found[fmt.Sprint(c.keys)] = true
+		}
+		for _, keys := range tt.present {
+			if !found[fmt.Sprint(keys)] {
+				t.Fatalf("hand %v: expected candidate %v in %v", tt.hand, keys, found)
+			}
+		}
+		for _, keys := range tt.absent {
+			if found[fmt.Sprint(keys)] {
+				t.Fatalf("hand %v: unexpected candidate %v", tt.hand, keys)
+			}
+		}
+	}
+}
+
+func TestPlay(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy Strategy
+		hand     []int
+		last     []int
+		master   bool
+		answer   string
+	}{
+		{name: "greedy smallest single", strategy: Greedy, hand: []int{4, 6, 9, 9}, last: []int{5}, answer: "6"},
+		{name: "greedy bomb last", strategy: Greedy, hand: []int{4, 7, 7, 7, 7}, last: []int{2}, answer: "7777"},
+		{name: "greedy pass", strategy: Greedy, hand: []int{3, 4}, last: []int{5}, answer: "p"},
+		{name: "greedy whole hand", strategy: Greedy, hand: []int{8, 8}, last: []int{6, 6}, answer: "88"},
+		{name: "planner keeps the straight", strategy: Planner, hand: []int{3, 4, 5, 6, 7, 9, 13}, last: []int{8}, answer: "9"},
+		{name: "planner empty hand", strategy: Planner, hand: []int{}, master: true, answer: "p"},
+	}
+	for _, tt := range tests {
+		game := newGame(tt.hand, []int{3, 3, 4, 4, 5, 5})
+		if tt.last != nil {
+			game.LastPlayer = 2
+			game.LastFaces = &poker.ParseFaces(pokersOf(tt.last...), game.Rules)[0]
+		}
+		if ans := tt.strategy.Play(game, 1, tt.master); ans != tt.answer {
+			t.Fatalf("%s: expected %q, got %q", tt.name, tt.answer, ans)
+		}
+	}
+}
+
+func TestLeadEmpty(t *testing.T) {
+	if c := lead(newGame(nil), 1, nil); len(c.keys) != 0 {
+		t.Fatalf("expected nothing to lead, got %v", c.keys)
+	}
+}
+
+func TestCanBeat(t *testing.T) {
+	tests := []struct {
+		hand []int
+		last []int
+		can  bool
+	}{
+		{hand: []int{3, 6}, last: []int{5}, can: true},
+		{hand: []int{3, 4}, last: []int{5}, can: false},
+		{hand: []int{3, 4}, can: true},
+		{hand: []int{6, 6, 9}, last: []int{5, 5}, can: true},
+		{hand: []int{6, 9, 9, 9}, last: []int{5, 5}, can: true},
+		{hand: []int{3, 7, 7, 7, 7}, last: []int{2, 2}, can: true},
+		{hand: []int{7, 7, 7, 7, 8}, last: []int{14, 15}, can: false},
+		{hand: []int{3, 4, 5, 6, 8}, last: []int{4, 5, 6, 7, 8}, can: false},
+	}
+	for _, tt := range tests {
+		game := newGame(tt.hand)
+		if tt.last != nil {
+			game.LastFaces = &poker.ParseFaces(pokersOf(tt.last...), game.Rules)[0]
+		}
+		if can := CanBeat(game, 1); can != tt.can {
+			t.Fatalf("hand %v last %v: expected %v", tt.hand, tt.last, tt.can)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package rule
+
+import (
+	"github.com/ratel-online/core/model"
+)
+
+// FourPlayerRules 四人斗地主的规则，两副牌108张，8张底牌，炸弹按张数比大小，四张王的天王炸最大
+var FourPlayerRules = _fourPlayerRules{_rules{reserved: true}}
+
+type _fourPlayerRules struct {
+	_rules
+}
+
+// IsMax reports whether the faces are the four jokers, nothing can be played over them.
+func (r _fourPlayerRules) IsMax(faces model.Faces) bool {
+	if len(faces.Keys) != 4 {
+		return false
+	}
+	for _, key := range faces.Keys {
+		if key != 14 && key != 15 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,21 @@
+package rule
+
+// RunFastRules 跑得快的规则，没有底牌，两对即可成连对
+var RunFastRules = _runFastRules{}
+
+type _runFastRules struct {
+	_rules
+}
+
+func (r _runFastRules) IsStraight(faces []int, count int) bool {
+	if faces[len(faces)-1]-faces[0] != len(faces)-1 {
+		return false
+	}
+	if faces[len(faces)-1] > 12 {
+		return false
+	}
+	if count == 1 {
+		return len(faces) >= 5
+	}
+	return count > 1 && len(faces) >= 2
+}
@@ -0,0 +1,93 @@
+package game
+
+import (
+	"fmt"
+	"github.com/ratel-online/server/config"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/render"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// handleBid replaces the rob phase in the bid mode: everybody bids 1 to 3 once around the table,
+// a bid has to be higher than the last one, and a bid of 3 ends the bidding right away.
+// The highest bidder becomes landlord and its bid is the base score, in the duel every outbid concedes a poker.
+func handleBid(player *database.Player, game *database.Game) error {
+	if game.FirstPlayer == player.ID {
+		// 叫分转了一圈
+		if game.LastRob == 0 {
+			return redeal(player, game)
+		}
+		return electLandlord(player.RoomID, game, game.LastRob)
+	}
+	msg := ""
+	if game.FirstPlayer == 0 {
+		game.FirstPlayer = player.ID
+		msg = fmt.Sprintf("%s's turn to bid\n", player.Name)
+	}
+	timeout := config.Get().RobTimeoutOf(game.Type)
+	render.Publish(player.RoomID, render.NewRobPromptEvent(player, timeout, msg), player.ID)
+
+	game.Turn = player.ID
+	lowest := lowestBid(game)
+	for {
+		before := time.Now().Unix()
+		_ = render.Send(player, render.NewRobPromptEvent(player, timeout, fmt.Sprintf("Please bid %d ~ %d, or n to pass\n", lowest, consts.MaxBid)))
+		ans, err := askForBid(player, game, timeout)
+		if err != nil && err != consts.ErrorsExist {
+			ans = "n"
+		}
+		timeout -= time.Second * time.Duration(time.Now().Unix()-before)
+		ans = strings.ToLower(strings.TrimSpace(ans))
+		if ans == "n" || ans == "0" {
+			render.Publish(player.RoomID, render.NewBidEvent(player, 0, fmt.Sprintf("%s don't bid\n", player.Name)))
+			break
+		}
+		score, err := strconv.Atoi(ans)
+		if err != nil || score < lowest || score > consts.MaxBid {
+			_ = player.WriteError(consts.ErrorsInputInvalid)
+			continue
+		}
+		msg = fmt.Sprintf("%s bid %d\n", player.Name, score)
+		if game.LastRob != 0 && game.IsDuel() {
+			// 二人斗地主和抢地主一样，每压过一次叫分让一张牌
+			game.Concessions++
+			msg = fmt.Sprintf("%s bid %d, concessions: %d\n", player.Name, score, game.Concessions)
+		}
+		game.Base = score
+		game.LastRob = player.ID
+		render.Publish(player.RoomID, render.NewBidEvent(player, score, msg))
+		if score == consts.MaxBid {
+			return electLandlord(player.RoomID, game, player.ID)
+		}
+		break
+	}
+	if !game.Notify(game.NextPlayer(player.ID), stateRob) {
+		return consts.ErrorsChanClosed
+	}
+	return nil
+}
+
+// lowestBid is the lowest score the player may bid, one more than the highest bid so far.
+func lowestBid(game *database.Game) int {
+	if game.LastRob == 0 {
+		return 1
+	}
+	return game.Base + 1
+}
+
+// askForBid lets robots and the autopilot bid the lowest score when they would rob.
+func askForBid(player *database.Player, game *database.Game, timeout time.Duration) (string, error) {
+	if player.Auto() {
+		time.Sleep(consts.AutopilotDelay)
+		if StrategyOf(player).Rob(game, player.ID) {
+			return strconv.Itoa(lowestBid(game)), nil
+		}
+		return "n", nil
+	}
+	ans, err := player.AskForString(timeout)
+	CountTimeouts(player, "rob", err)
+	return ans, err
+}
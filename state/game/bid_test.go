@@ -0,0 +1,140 @@
+package game
+
+import (
+	"github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/network"
+	"github.com/ratel-online/core/protocol"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/rule"
+	"testing"
+)
+
+// script answers the reads of a player in order, every read starts with consts.IsStart.
+type script struct {
+	answers []string
+	packets chan *protocol.Packet
+}
+
+func (s *script) Read() (*protocol.Packet, error) { return <-s.packets, nil }
+func (s *script) Close() error                    { return nil }
+func (s *script) IP() string                      { return "127.0.0.1" }
+func (s *script) Write(msg protocol.Packet) error {
+	if string(msg.Body) == consts.IsStart && len(s.answers) > 0 {
+		s.packets <- &protocol.Packet{Body: []byte(s.answers[0])}
+		s.answers = s.answers[1:]
+	}
+	return nil
+}
+
+var scriptIds = consts.GuestIDOffset
+
+// newScriptedGame seats a scripted player for each list of answers and deals them a landlord game.
+func newScriptedGame(props map[string]bool, answers ...[]string) (*database.Game, []*script) {
+	scripts := make([]*script, 0, len(answers))
+	players := make([]int64, 0, len(answers))
+	states := map[int64]chan int{}
+	for _, a := range answers {
+		scriptIds++
+		s := &script{answers: a, packets: make(chan *protocol.Packet, 1)}
+		player, _, _ := database.Login(network.Wrapper(s), &model.AuthInfo{ID: scriptIds, Name: "nico"}, consts.ProtocolText)
+		go player.Listening()
+		scripts = append(scripts, s)
+		players = append(players, player.ID)
+		states[player.ID] = make(chan int, 1)
+	}
+	distributes, _, decks := distribute(len(players), false, rule.LandlordRules)
+	pokers := map[int64]model.Pokers{}
+	for i, id := range players {
+		pokers[id] = distributes[i]
+	}
+	game := &database.Game{
+		Type:       consts.GameTypeClassic,
+		States:     states,
+		Players:    players,
+		Groups:     map[int64]int{},
+		Pokers:     pokers,
+		Additional: distributes[len(distributes)-1],
+		Base:       consts.BaseScore,
+		Multiple:   1,
+		Decks:      decks,
+		Properties: props,
+		Rules:      rule.LandlordRules,
+	}
+	return game, scripts
+}
+
+// drive hands the turns to handle until the game moves on to another state, it returns who is notified of which state.
+func drive(t *testing.T, game *database.Game, state int, handle func(*database.Player, *database.Game) error) (int64, int) {
+	for i := 0; i < 20; i++ {
+		id, s := turn(game)
+		if s != state {
+			return id, s
+		}
+		if err := handle(database.GetPlayer(id), game); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Fatal("too many turns")
+	return 0, 0
+}
+
+// turn returns the player notified of its turn.
+func turn(game *database.Game) (int64, int) {
+	for _, id := range game.Players {
+		select {
+		case s := <-game.States[id]:
+			return id, s
+		default:
+		}
+	}
+	return 0, 0
+}
+
+func TestHandleBid(t *testing.T) {
+	tests := []struct {
+		name     string
+		answers  [][]string
+		landlord int
+		base     int
+		left     []int
+	}{
+		// 叫分必须高于上家，转一圈后最高者当地主
+		{name: "highest", answers: [][]string{{"1"}, {"1", "2"}, {"n"}}, landlord: 1, base: 2, left: []int{0, 0, 0}},
+		// 叫3分立即结束，后面的玩家不再叫分
+		{name: "three", answers: [][]string{{"1"}, {"3"}, {"2"}}, landlord: 1, base: 3, left: []int{0, 0, 1}},
+		{name: "first", answers: [][]string{{"2"}, {"n"}, {"4", "0"}}, landlord: 0, base: 2, left: []int{0, 0, 0}},
+	}
+	for _, tt := range tests {
+		game, scripts := newScriptedGame(map[string]bool{consts.RoomPropsBid: true}, tt.answers...)
+		game.Notify(game.Players[0], stateRob)
+		id, state := drive(t, game, stateRob, handleBid)
+		landlord := game.Players[tt.landlord]
+		if id != landlord || state != statePlay || !game.IsLandlord(landlord) || game.Base != tt.base || len(game.Pokers[landlord]) != 20 {
+			t.Fatalf("%s: unexpected landlord %d state %d base %d", tt.name, id, state, game.Base)
+		}
+		for i, s := range scripts {
+			if len(s.answers) != tt.left[i] {
+				t.Fatalf("%s: player %d expected %d answers left, got %v", tt.name, i, tt.left[i], s.answers)
+			}
+		}
+	}
+}
+
+func TestHandleBidRedeal(t *testing.T) {
+	game, _ := newScriptedGame(map[string]bool{consts.RoomPropsBid: true}, []string{"n"}, []string{"n"}, []string{"n"})
+	game.Record(database.MatchEvent{Type: database.MatchEventDeal})
+	game.Notify(game.Players[0], stateRob)
+	_, state := drive(t, game, stateRob, handleBid)
+	if state != stateReset {
+		t.Fatalf("expected everybody to be redealt, got state %d", state)
+	}
+	for _, id := range game.Players[1:] {
+		if s := <-game.States[id]; s != stateReset {
+			t.Fatalf("expected %d to be redealt, got state %d", id, s)
+		}
+	}
+	if game.FirstPlayer != 0 || game.LastRob != 0 || len(game.Pokers[game.Players[0]]) != 17 || len(game.Additional) != 3 || len(game.RecordedEvents()) != 0 {
+		t.Fatalf("unexpected redealt game %+v", game)
+	}
+}
@@ -0,0 +1,103 @@
+package game
+
+import (
+	"fmt"
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/server/config"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/render"
+	"strings"
+	"time"
+)
+
+// 加倍阶段的倍数：农民加倍x2，地主超级加倍x4，地主明牌x2
+const (
+	doubleMultiple      = 2
+	superDoubleMultiple = 4
+	openHandMultiple    = 2
+)
+
+// handleDouble runs the doubling phase after the landlord was chosen, the peasants in turn may double,
+// then the landlord may double super or open its hand to everyone, and plays first afterwards.
+// Timeouts count as no.
+func handleDouble(player *database.Player, game *database.Game) error {
+	landlord := game.IsLandlord(player.ID)
+	timeout := config.Get().RobTimeoutOf(game.Type)
+	prompt := "Do you want to double? x2 (y or n)\n"
+	if landlord {
+		prompt = "Do you want to double super x4 (s), open your hand x2 (o), or not (n)?\n"
+	}
+	render.Publish(player.RoomID, render.NewRobPromptEvent(player, timeout, fmt.Sprintf("%s's turn to double\n", player.Name)), player.ID)
+	game.Turn = player.ID
+	for {
+		before := time.Now().Unix()
+		_ = render.Send(player, render.NewRobPromptEvent(player, timeout, prompt))
+		ans, err := askForDouble(player, game, landlord, timeout)
+		if err != nil {
+			ans = "n"
+		}
+		timeout -= time.Second * time.Duration(time.Now().Unix()-before)
+		ans = strings.ToLower(strings.TrimSpace(ans))
+		var event render.DoubleEvent
+		switch {
+		case ans == "n":
+			event = render.NewDoubleEvent(player, 1, nil, fmt.Sprintf("%s don't double\n", player.Name))
+		case ans == "y" && !landlord:
+			game.Multiple *= doubleMultiple
+			event = render.NewDoubleEvent(player, doubleMultiple, nil, fmt.Sprintf("%s doubled, multiple x%d, current multiple: %d\n", player.Name, doubleMultiple, game.Multiple))
+		case ans == "s" && landlord:
+			game.Multiple *= superDoubleMultiple
+			event = render.NewDoubleEvent(player, superDoubleMultiple, nil, fmt.Sprintf("%s doubled super, multiple x%d, current multiple: %d\n", player.Name, superDoubleMultiple, game.Multiple))
+		case ans == "o" && landlord:
+			game.Multiple *= openHandMultiple
+			game.OpenHand = true
+			pokers := append(modelx.Pokers{}, game.Pokers[player.ID]...)
+			event = render.NewDoubleEvent(player, openHandMultiple, pokers, fmt.Sprintf("%s opened the hand: %s, multiple x%d, current multiple: %d\n", player.Name, pokers.OaaString(), openHandMultiple, game.Multiple))
+		default:
+			_ = player.WriteError(consts.ErrorsInputInvalid)
+			continue
+		}
+		render.Publish(player.RoomID, event)
+		break
+	}
+	// 地主最后选择，之后由地主先出牌
+	state, next := stateDouble, game.NextPlayer(player.ID)
+	if landlord {
+		state, next = statePlay, player.ID
+	}
+	if !game.Notify(next, state) {
+		return consts.ErrorsChanClosed
+	}
+	return nil
+}
+
+// askForDouble lets robots and the autopilot double when they would rob, they never open the hand.
+func askForDouble(player *database.Player, game *database.Game, landlord bool, timeout time.Duration) (string, error) {
+	if player.Auto() {
+		time.Sleep(consts.AutopilotDelay)
+		if !StrategyOf(player).Rob(game, player.ID) {
+			return "n", nil
+		}
+		if landlord {
+			return "s", nil
+		}
+		return "y", nil
+	}
+	ans, err := player.AskForString(timeout)
+	CountTimeouts(player, "rob", err)
+	return ans, err
+}
+
+// openHand describes the hand of the open landlord to the other players.
+func openHand(game *database.Game, playerId int64) string {
+	if !game.OpenHand || game.IsLandlord(playerId) {
+		return ""
+	}
+	for _, id := range game.Players {
+		if game.IsLandlord(id) {
+			return fmt.Sprintf("Landlord %s opened the hand: %s\n", database.GetPlayer(id).Name, game.Pokers[id].OaaString())
+		}
+	}
+	return ""
+}
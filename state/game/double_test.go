@@ -0,0 +1,38 @@
+package game
+
+import (
+	"github.com/ratel-online/server/consts"
+	"testing"
+)
+
+func TestHandleDouble(t *testing.T) {
+	tests := []struct {
+		name     string
+		answers  [][]string
+		multiple int
+		openHand bool
+	}{
+		// 农民不能超级加倍，地主不能普通加倍
+		{name: "peasants", answers: [][]string{{"y", "n"}, {"s", "y"}, {"n"}}, multiple: 2},
+		{name: "super", answers: [][]string{{"s"}, {"y"}, {"y"}}, multiple: 16},
+		{name: "open", answers: [][]string{{"o"}, {"n"}, {"o", "x", "n"}}, multiple: 2, openHand: true},
+	}
+	for _, tt := range tests {
+		game, scripts := newScriptedGame(map[string]bool{consts.RoomPropsDouble: true}, tt.answers...)
+		landlord := game.Players[0]
+		game.Groups[landlord] = 1
+		game.Notify(game.Players[1], stateDouble)
+		id, state := drive(t, game, stateDouble, handleDouble)
+		if id != landlord || state != statePlay || game.Multiple != tt.multiple || game.OpenHand != tt.openHand {
+			t.Fatalf("%s: unexpected %d state %d multiple %d open %v", tt.name, id, state, game.Multiple, game.OpenHand)
+		}
+		for i, s := range scripts {
+			if len(s.answers) != 0 {
+				t.Fatalf("%s: player %d expected no answers left, got %v", tt.name, i, s.answers)
+			}
+		}
+		if tt.openHand && openHand(game, game.Players[1]) == "" || openHand(game, landlord) != "" {
+			t.Fatalf("%s: unexpected open hand", tt.name)
+		}
+	}
+}
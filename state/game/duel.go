@@ -0,0 +1,64 @@
+package game
+
+import (
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/util/poker"
+	"github.com/ratel-online/server/database"
+)
+
+// 二人斗地主：去掉3和4共46张牌，每人17张，3张底牌，剩下的9张扣下不用
+const (
+	duelHand   = 17
+	duelPocket = 3
+)
+
+// duelKeys are the keys taken out of the deck of two players.
+var duelKeys = map[int]bool{3: true, 4: true}
+
+// distribute deals the hands followed by the pocket, two players of the landlord game get the duel deck
+// and the pokers put aside face down.
+func distribute(players int, dontShuffle bool, rules poker.Rules) ([]modelx.Pokers, modelx.Pokers, int) {
+	if players == 2 && rules.Reserved() {
+		distributes, removed := distributeDuel(dontShuffle, rules)
+		return distributes, removed, 1
+	}
+	distributes, decks := poker.Distribute(players, dontShuffle, rules)
+	return distributes, nil, decks
+}
+
+func distributeDuel(dontShuffle bool, rules poker.Rules) ([]modelx.Pokers, modelx.Pokers) {
+	base := poker.GetDontShuffleBase()
+	pokers := make(modelx.Pokers, 0, len(base))
+	for _, p := range base {
+		if !duelKeys[p.Key] {
+			p.Val = rules.Value(p.Key)
+			pokers = append(pokers, p)
+		}
+	}
+	if dontShuffle {
+		pokers.Shuffle(len(pokers), 4)
+	} else {
+		pokers.Shuffle(len(pokers), 1)
+	}
+	distributes := []modelx.Pokers{
+		append(modelx.Pokers{}, pokers[:duelHand]...),
+		append(modelx.Pokers{}, pokers[duelHand:2*duelHand]...),
+		append(modelx.Pokers{}, pokers[2*duelHand:2*duelHand+duelPocket]...),
+	}
+	for _, pokers := range distributes {
+		pokers.SortByValue()
+	}
+	removed := append(modelx.Pokers{}, pokers[2*duelHand+duelPocket:]...)
+	removed.SortByValue()
+	return distributes, removed
+}
+
+// duelWon reports whether the player has won, the peasant of the duel wins once
+// the pokers in hand are no more than the concessions of the landlord.
+func duelWon(game *database.Game, playerId int64) bool {
+	left := len(game.Pokers[playerId])
+	if left == 0 {
+		return true
+	}
+	return game.IsDuel() && !game.IsLandlord(playerId) && left <= game.Concessions
+}
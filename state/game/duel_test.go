@@ -0,0 +1,138 @@
+package game
+
+import (
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/render"
+	"github.com/ratel-online/server/rule"
+	"testing"
+)
+
+func TestDistributeDuel(t *testing.T) {
+	for _, dontShuffle := range []bool{false, true} {
+		distributes, removed := distributeDuel(dontShuffle, rule.LandlordRules)
+		sizes := []int{len(distributes[0]), len(distributes[1]), len(distributes[2]), len(removed)}
+		if len(distributes) != 3 || sizes[0] != 17 || sizes[1] != 17 || sizes[2] != 3 || sizes[3] != 9 {
+			t.Fatalf("unexpected split %v", sizes)
+		}
+		for _, pokers := range append(distributes, removed) {
+			for _, p := range pokers {
+				if duelKeys[p.Key] {
+					t.Fatalf("unexpected poker %s in the duel deck", p.Desc)
+				}
+			}
+		}
+	}
+}
+
+func TestDuelWon(t *testing.T) {
+	landlord, peasant := int64(1), int64(2)
+	tests := []struct {
+		player      int64
+		left        int
+		concessions int
+		won         bool
+	}{
+		{player: peasant, left: 0, won: true},
+		{player: peasant, left: 1, won: false},
+		{player: peasant, left: 2, concessions: 2, won: true},
+		{player: peasant, left: 3, concessions: 2, won: false},
+		{player: landlord, left: 1, concessions: 2, won: false},
+		{player: landlord, left: 0, concessions: 2, won: true},
+	}
+	for _, tt := range tests {
+		game := &database.Game{
+			Players:     []int64{landlord, peasant},
+			Groups:      map[int64]int{landlord: 1, peasant: 0},
+			Pokers:      map[int64]modelx.Pokers{tt.player: make(modelx.Pokers, tt.left)},
+			Concessions: tt.concessions,
+			Rules:       rule.LandlordRules,
+		}
+		if won := duelWon(game, tt.player); won != tt.won {
+			t.Fatalf("player %d with %d left and %d concessions: expected won %v", tt.player, tt.left, tt.concessions, tt.won)
+		}
+	}
+}
+
+func TestRandomUniversals(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		universals := randomUniversals(true)
+		for _, key := range universals {
+			if duelKeys[key] || key > 13 {
+				t.Fatalf("unexpected universals %v in the duel", universals)
+			}
+		}
+		if universals[0] == universals[1] {
+			t.Fatalf("expected two universals, got %v", universals)
+		}
+	}
+}
+
+func TestHandleBidDuel(t *testing.T) {
+	tests := []struct {
+		answers     [][]string
+		landlord    int
+		concessions int
+	}{
+		{answers: [][]string{{"1"}, {"2"}}, landlord: 1, concessions: 1},
+		{answers: [][]string{{"n"}, {"2"}}, landlord: 1},
+		{answers: [][]string{{"1"}, {"n"}}, landlord: 0},
+	}
+	for _, tt := range tests {
+		game, _ := newScriptedGame(map[string]bool{consts.RoomPropsBid: true}, tt.answers...)
+		game.Notify(game.Players[0], stateRob)
+		id, _ := drive(t, game, stateRob, handleBid)
+		if id != game.Players[tt.landlord] || game.Concessions != tt.concessions {
+			t.Fatalf("answers %v: unexpected landlord %d and concessions %d", tt.answers, id, game.Concessions)
+		}
+	}
+}
+
+func TestDealDuel(t *testing.T) {
+	distributes, removed := distributeDuel(false, rule.LandlordRules)
+	game := &database.Game{
+		Players:    []int64{1, 2},
+		Pokers:     map[int64]modelx.Pokers{1: distributes[0], 2: distributes[1]},
+		Additional: distributes[2],
+		Removed:    removed,
+		Properties: map[string]bool{},
+		Rules:      rule.LandlordRules,
+	}
+	// 不存在的房间，只有记录对局的订阅者
+	cancel := render.Subscribe(-1, func(event render.Event) { record(game, event) })
+	defer cancel()
+	deal(-1, game)
+	events := game.RecordedEvents()
+	if len(events) != 1 || events[0].Type != database.MatchEventDeal || events[0].Removed.String() != removed.String() {
+		t.Fatalf("expected the removed pokers in the deal, got %+v", events)
+	}
+}
+
+func TestDistributeFour(t *testing.T) {
+	for _, dontShuffle := range []bool{false, true} {
+		distributes, removed, decks := distribute(4, dontShuffle, rule.FourPlayerRules)
+		if len(distributes) != 5 || removed != nil || decks != 2 || len(distributes[4]) != 8 {
+			t.Fatalf("unexpected split of %d hands, %d decks", len(distributes), decks)
+		}
+		counts := map[int]int{}
+		for i, pokers := range distributes {
+			if i < 4 && len(pokers) != 25 {
+				t.Fatalf("expected 25 pokers for player %d, got %d", i, len(pokers))
+			}
+			for _, p := range pokers {
+				counts[p.Key]++
+			}
+		}
+		// 两副牌共108张
+		for key := 1; key <= 15; key++ {
+			expected := 8
+			if key > 13 {
+				expected = 2
+			}
+			if counts[key] != expected {
+				t.Fatalf("expected %d of key %d, got %d", expected, key, counts[key])
+			}
+		}
+	}
+}
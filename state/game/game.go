@@ -6,8 +6,12 @@ import (
 	"github.com/ratel-online/core/log"
 	modelx "github.com/ratel-online/core/model"
 	"github.com/ratel-online/core/util/poker"
+	"github.com/ratel-online/server/config"
 	"github.com/ratel-online/server/consts"
 	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/metrics"
+	"github.com/ratel-online/server/render"
+	"github.com/ratel-online/server/robot"
 	"github.com/ratel-online/server/skill"
 	"math/rand"
 	"strconv"
@@ -22,6 +26,7 @@ var (
 	statePlay    = 2
 	stateReset   = 3
 	stateWaiting = 4
+	stateDouble  = 5
 )
 
 func (g *Game) Next(player *database.Player) (consts.StateID, error) {
@@ -30,6 +35,7 @@ func (g *Game) Next(player *database.Player) (consts.StateID, error) {
 		return 0, player.WriteError(consts.ErrorsExist)
 	}
 	game := room.Game
+	player.SetAuto(false)
 	buf := bytes.Buffer{}
 	if game.Properties[consts.RoomPropsLaiZi] {
 		if game.Properties[consts.RoomPropsSkill] {
@@ -43,16 +49,19 @@ func (g *Game) Next(player *database.Player) (consts.StateID, error) {
 	} else {
 		buf.WriteString(fmt.Sprintf("Game starting!\n"))
 	}
+	if game.IsDuel() {
+		buf.WriteString("Two-player game! 3 and 4 are taken out, 9 pokers are put aside face down, every rob after the first concedes one poker to the peasant\n")
+	}
 	if game.Properties[consts.RoomPropsSkill] {
 		buf.WriteString(fmt.Sprintf("Got skill %s\n", skill.Skills[consts.SkillID(game.Skills[player.ID])].Name()))
 	}
 	buf.WriteString(fmt.Sprintf("Your pokers: %s\n", game.Pokers[player.ID].String()))
-	_ = player.WriteString(buf.String())
+	_ = render.Send(player, render.NewHandEvent(game.Pokers[player.ID], buf.String()))
 	for {
 		if room.State == consts.RoomStateWaiting {
 			return consts.StateWaiting, nil
 		}
-		state := <-game.States[player.ID]
+		state := game.Wait(player.ID)
 		switch state {
 		case stateRob:
 			if game.Properties[consts.RoomPropsSkill] {
@@ -62,7 +71,15 @@ func (g *Game) Next(player *database.Player) (consts.StateID, error) {
 					game.Pokers[id].SetOaa(game.Universals...)
 					game.Pokers[id].SortByOaaValue()
 				}
-				game.States[player.ID] <- statePlay
+				if !game.Notify(player.ID, statePlay) {
+					return 0, consts.ErrorsChanClosed
+				}
+			} else if game.Properties[consts.RoomPropsBid] {
+				err := handleBid(player, game)
+				if err != nil {
+					log.Error(err)
+					return 0, err
+				}
 			} else {
 				err := handleRob(player, game)
 				if err != nil {
@@ -73,7 +90,9 @@ func (g *Game) Next(player *database.Player) (consts.StateID, error) {
 		case stateReset:
 			if player.ID == room.Creator {
 				rand.Seed(time.Now().UnixNano())
-				game.States[game.Players[rand.Intn(len(game.States))]] <- stateRob
+				if !game.Notify(game.Players[rand.Intn(len(game.States))], stateRob) {
+					return 0, consts.ErrorsChanClosed
+				}
 			}
 			return 0, nil
 		case statePlay:
@@ -82,6 +101,12 @@ func (g *Game) Next(player *database.Player) (consts.StateID, error) {
 				log.Error(err)
 				return 0, err
 			}
+		case stateDouble:
+			err := handleDouble(player, game)
+			if err != nil {
+				log.Error(err)
+				return 0, err
+			}
 		case stateWaiting:
 			return consts.StateWaiting, nil
 		default:
@@ -97,66 +122,50 @@ func (*Game) Exit(player *database.Player) consts.StateID {
 func handleRob(player *database.Player, game *database.Game) error {
 	if game.FirstPlayer == player.ID && !game.FinalRob {
 		if game.FirstRob == 0 {
-			err := resetGame(game)
-			if err != nil {
-				log.Error(err)
-				return err
-			}
-			database.Broadcast(player.RoomID, "All players have give up the landlord, restarting...\n")
-			for _, playerId := range game.Players {
-				game.States[playerId] <- stateReset
-			}
+			return redeal(player, game)
 		} else if game.FirstRob == game.LastRob {
-			landlord := database.GetPlayer(game.LastRob)
-			game.FirstPlayer = landlord.ID
-			game.LastPlayer = landlord.ID
-			game.Groups[landlord.ID] = 1
-			game.Pokers[landlord.ID] = append(game.Pokers[landlord.ID], game.Additional...)
-			game.Pokers[landlord.ID].SortByOaaValue()
-
-			buf := bytes.Buffer{}
-			if game.Properties[consts.RoomPropsLaiZi] {
-				buf.WriteString(fmt.Sprintf("%s became landlord, got pokers: %s, last universal: %s\n", landlord.Name, game.Additional.String(), poker.GetDesc(game.Universals[1])))
-				for _, pokers := range game.Pokers {
-					pokers.SetOaa(game.Universals...)
-					pokers.SortByOaaValue()
-				}
-			} else {
-				buf.WriteString(fmt.Sprintf("%s became landlord, got pokers: %s\n", landlord.Name, game.Additional.String()))
-			}
-			database.Broadcast(player.RoomID, buf.String())
-			game.States[landlord.ID] <- statePlay
+			return electLandlord(player.RoomID, game, game.LastRob)
 		} else {
 			game.FinalRob = true
-			game.States[game.FirstRob] <- stateRob
+			if !game.Notify(game.FirstRob, stateRob) {
+				return consts.ErrorsChanClosed
+			}
 		}
 		return nil
 	}
+	msg := ""
 	if game.FirstPlayer == 0 {
 		game.FirstPlayer = player.ID
-		database.Broadcast(player.RoomID, fmt.Sprintf("%s's turn to rob\n", player.Name), player.ID)
+		msg = fmt.Sprintf("%s's turn to rob\n", player.Name)
 	}
+	timeout := config.Get().RobTimeoutOf(game.Type)
+	render.Publish(player.RoomID, render.NewRobPromptEvent(player, timeout, msg), player.ID)
 
-	timeout := consts.RobTimeout
+	game.Turn = player.ID
 	for {
 		before := time.Now().Unix()
-		_ = player.WriteString("Are you want to become landlord? (y or n)\n")
-		ans, err := player.AskForString(timeout)
+		_ = render.Send(player, render.NewRobPromptEvent(player, timeout, "Are you want to become landlord? (y or n)\n"))
+		ans, err := askForRob(player, game, timeout)
 		if err != nil && err != consts.ErrorsExist {
 			ans = "n"
 		}
 		timeout -= time.Second * time.Duration(time.Now().Unix()-before)
 		ans = strings.ToLower(ans)
 		if ans == "y" {
+			msg := fmt.Sprintf("%s rob\n", player.Name)
 			if game.FirstRob == 0 {
 				game.FirstRob = player.ID
+			} else if game.IsDuel() {
+				// 二人斗地主每抢一次地主让一张牌
+				game.Concessions++
+				msg = fmt.Sprintf("%s rob, concessions: %d\n", player.Name, game.Concessions)
 			}
 			game.LastRob = player.ID
 			game.Multiple *= 2
-			database.Broadcast(player.RoomID, fmt.Sprintf("%s rob\n", player.Name))
+			render.Publish(player.RoomID, render.NewRobEvent(player, true, msg))
 			break
 		} else if ans == "n" {
-			database.Broadcast(player.RoomID, fmt.Sprintf("%s don't rob\n", player.Name))
+			render.Publish(player.RoomID, render.NewRobEvent(player, false, fmt.Sprintf("%s don't rob\n", player.Name)))
 			break
 		} else {
 			_ = player.WriteError(consts.ErrorsInputInvalid)
@@ -166,26 +175,92 @@ func handleRob(player *database.Player, game *database.Game) error {
 	if game.FinalRob {
 		game.FinalRob = false
 		game.FirstRob = game.LastRob
-		game.States[game.FirstPlayer] <- stateRob
+		if !game.Notify(game.FirstPlayer, stateRob) {
+			return consts.ErrorsChanClosed
+		}
 	} else {
-		game.States[game.NextPlayer(player.ID)] <- stateRob
+		if !game.Notify(game.NextPlayer(player.ID), stateRob) {
+			return consts.ErrorsChanClosed
+		}
+	}
+	return nil
+}
+
+// redeal deals a new hand after all players gave up the landlord.
+func redeal(player *database.Player, game *database.Game) error {
+	err := resetGame(player.RoomID, game)
+	if err != nil {
+		log.Error(err)
+		return err
+	}
+	database.Broadcast(player.RoomID, "All players have give up the landlord, restarting...\n")
+	for _, playerId := range game.Players {
+		if !game.Notify(playerId, stateReset) {
+			return consts.ErrorsChanClosed
+		}
+	}
+	return nil
+}
+
+// electLandlord hands the pocket to the landlord, who plays first.
+func electLandlord(roomId int64, game *database.Game, landlordId int64) error {
+	landlord := database.GetPlayer(landlordId)
+	game.FirstPlayer = landlord.ID
+	game.LastPlayer = landlord.ID
+	game.Groups[landlord.ID] = 1
+	pocket := append(modelx.Pokers{}, game.Additional...)
+	game.Pokers[landlord.ID] = append(game.Pokers[landlord.ID], game.Additional...)
+	game.Pokers[landlord.ID].SortByOaaValue()
+
+	buf := bytes.Buffer{}
+	var universals []int
+	if game.Properties[consts.RoomPropsLaiZi] {
+		universals = game.Universals
+		buf.WriteString(fmt.Sprintf("%s became landlord, got pokers: %s, last universal: %s\n", landlord.Name, game.Additional.String(), poker.GetDesc(game.Universals[1])))
+		for _, pokers := range game.Pokers {
+			pokers.SetOaa(game.Universals...)
+			pokers.SortByOaaValue()
+		}
+	} else {
+		buf.WriteString(fmt.Sprintf("%s became landlord, got pokers: %s\n", landlord.Name, game.Additional.String()))
+	}
+	render.Publish(roomId, render.NewLandlordEvent(landlord, pocket, universals, buf.String()))
+	if game.Properties[consts.RoomPropsDouble] {
+		// 农民依次选择加倍，地主最后
+		if !game.Notify(game.NextPlayer(landlord.ID), stateDouble) {
+			return consts.ErrorsChanClosed
+		}
+		return nil
+	}
+	if !game.Notify(landlord.ID, statePlay) {
+		return consts.ErrorsChanClosed
 	}
 	return nil
 }
 
 func playing(player *database.Player, game *database.Game, master bool, playTimes int) error {
 	timeout := game.PlayTimeOut[player.ID]
+	attempts := 0
 	for {
+		attempts++
 		buf := bytes.Buffer{}
 		buf.WriteString("\n")
 		if !master && len(game.LastPokers) > 0 {
 			buf.WriteString(fmt.Sprintf("Last player: %s (%s), played: %s\n", database.GetPlayer(game.LastPlayer).Name, game.Team(game.LastPlayer), game.LastPokers.String()))
 		}
-		buf.WriteString(fmt.Sprintf("Timeout: %ds, pokers: %s\n", int(timeout.Seconds()), game.Pokers[player.ID].String()))
-		_ = player.WriteString(buf.String())
+		buf.WriteString(openHand(game, player.ID))
+		if game.IsDuel() {
+			buf.WriteString(fmt.Sprintf("Concessions: %d, the peasant wins with %d pokers left\n", game.Concessions, game.Concessions))
+		}
+		buf.WriteString(fmt.Sprintf("Timeout: %ds, multiple: %d, pokers: %s\n", int(timeout.Seconds()), game.Multiple, game.Pokers[player.ID].String()))
+		_ = render.Send(player, render.NewHandEvent(game.Pokers[player.ID], buf.String()))
 		before := time.Now().Unix()
 		pokers := game.Pokers[player.ID]
-		ans, err := player.AskForString(timeout)
+		ans, err := askForPlay(player, game, master, timeout, attempts)
+		if database.GetRoom(player.RoomID) == nil {
+			// 房间在等待出牌时被解散
+			return consts.ErrorsChanClosed
+		}
 		if err != nil {
 			if master {
 				ans = poker.GetAlias(pokers[0].Key)
@@ -202,14 +277,18 @@ func playing(player *database.Player, game *database.Game, master bool, playTime
 		} else if ans == "ls" || ans == "v" {
 			viewGame(game, player)
 			continue
+		} else if room := database.GetRoom(player.RoomID); room != nil && render.Query(player, ans, room.Type) {
+			continue
 		} else if ans == "p" || ans == "pass" {
 			if master {
 				_ = player.WriteError(consts.ErrorsHaveToPlay)
 				continue
 			} else {
 				nextPlayer := database.GetPlayer(game.NextPlayer(player.ID))
-				database.Broadcast(player.RoomID, fmt.Sprintf("%s passed, next %s\n", player.Name, nextPlayer.Name))
-				game.States[nextPlayer.ID] <- statePlay
+				render.Publish(player.RoomID, render.NewPassEvent(player, game.LastFaces, fmt.Sprintf("%s passed, next %s\n", player.Name, nextPlayer.Name)))
+				if !game.Notify(nextPlayer.ID, statePlay) {
+					return consts.ErrorsChanClosed
+				}
 				return nil
 			}
 		}
@@ -253,18 +332,18 @@ func playing(player *database.Player, game *database.Game, master bool, playTime
 			invalid = true
 		}
 		if invalid {
-			database.BroadcastChat(player, fmt.Sprintf("%s say: %s\n", player.Name, ans))
+			render.Chat(player, ans)
 			continue
 		}
 		lastFaces := game.LastFaces
 		if !master && lastFaces != nil {
-			if isMax(game, *lastFaces) {
+			if game.IsMax(*lastFaces) {
 				_ = player.WriteString(fmt.Sprintf("%s\n", consts.ErrorsPokersFacesInvalid.Error()))
 				continue
 			}
 			access := false
 			for _, faces := range facesArr {
-				if isMax(game, faces) || faces.Compare(*lastFaces) {
+				if game.IsMax(faces) || faces.Compare(*lastFaces) {
 					access = true
 					lastFaces = &faces
 					break
@@ -291,60 +370,169 @@ func playing(player *database.Player, game *database.Game, master bool, playTime
 		game.LastFaces = lastFaces
 		game.LastPokers = sells
 		game.Discards = append(game.Discards, sells...)
-		if len(pokers) == 0 {
-			database.Broadcast(player.RoomID, fmt.Sprintf("%s played %s, won the game! \n", player.Name, sells.OaaString()))
+		game.Plays[player.ID]++
+		if duelWon(game, player.ID) {
+			msg := fmt.Sprintf("%s played %s, won the game! \n", player.Name, sells.OaaString())
+			if len(pokers) > 0 {
+				msg = fmt.Sprintf("%s played %s, %d pokers left within the concessions, won the game! \n", player.Name, sells.OaaString(), len(pokers))
+			}
+			render.Publish(player.RoomID, render.NewPlayEvent(player, sells, lastFaces, msg))
+			countBomb(player, game, *lastFaces)
+			countSpring(player, game, player.ID)
+			var matchId int64
 			room := database.GetRoom(player.RoomID)
 			if room != nil {
+				matchId = settle(room, game, player.ID).ID
 				room.Lock()
 				room.Game = nil
 				room.State = consts.RoomStateWaiting
 				room.Unlock()
 			}
+			render.Publish(player.RoomID, render.NewGameOverEvent(player, matchId, fmt.Sprintf("Game over, replay id: %d\n", matchId)))
 			for _, playerId := range game.Players {
-				game.States[playerId] <- stateWaiting
+				if !game.Notify(playerId, stateWaiting) {
+					return consts.ErrorsChanClosed
+				}
 			}
 			return nil
 		}
 		if master {
 			playTimes--
 			if playTimes > 0 {
-				database.Broadcast(player.RoomID, fmt.Sprintf("%s played %s\n", player.Name, sells.OaaString()))
+				render.Publish(player.RoomID, render.NewPlayEvent(player, sells, lastFaces, fmt.Sprintf("%s played %s\n", player.Name, sells.OaaString())))
+				countBomb(player, game, *lastFaces)
 				return playing(player, game, master, playTimes)
 			}
 		}
 		nextPlayer := database.GetPlayer(game.NextPlayer(player.ID))
-		database.Broadcast(player.RoomID, fmt.Sprintf("%s played %s, next %s\n", player.Name, sells.OaaString(), nextPlayer.Name))
-		game.States[nextPlayer.ID] <- statePlay
+		render.Publish(player.RoomID, render.NewPlayEvent(player, sells, lastFaces, fmt.Sprintf("%s played %s, next %s\n", player.Name, sells.OaaString(), nextPlayer.Name)))
+		countBomb(player, game, *lastFaces)
+		if !game.Notify(nextPlayer.ID, statePlay) {
+			return consts.ErrorsChanClosed
+		}
 		return nil
 	}
 }
 
+// askForRob lets robots and the autopilot of offline or timed-out players answer.
+func askForRob(player *database.Player, game *database.Game, timeout time.Duration) (string, error) {
+	if player.Auto() {
+		time.Sleep(consts.AutopilotDelay)
+		if StrategyOf(player).Rob(game, player.ID) {
+			return "y", nil
+		}
+		return "n", nil
+	}
+	ans, err := player.AskForString(timeout)
+	CountTimeouts(player, "rob", err)
+	return ans, err
+}
+
+// askForPlay lets robots and the autopilot of offline or timed-out players answer,
+// an answer of them which got rejected falls back to the timeout behavior.
+func askForPlay(player *database.Player, game *database.Game, master bool, timeout time.Duration, attempts int) (string, error) {
+	if player.Auto() {
+		if attempts > 1 {
+			return "", consts.ErrorsTimeout
+		}
+		time.Sleep(consts.AutopilotDelay)
+		return StrategyOf(player).Play(game, player.ID, master), nil
+	}
+	ans, err := player.AskForString(timeout)
+	CountTimeouts(player, "play", err)
+	return ans, err
+}
+
+// StrategyOf returns the strategy of robots, the autopilot of humans plays greedy.
+func StrategyOf(player *database.Player) robot.Strategy {
+	if player.RobotLevel() == consts.RobotLevelHard {
+		return robot.Planner
+	}
+	return robot.Greedy
+}
+
+// CountTimeouts switches the player to autopilot after too many consecutive timeouts.
+func CountTimeouts(player *database.Player, turn string, err error) {
+	if err == nil {
+		player.ResetTimeouts()
+		return
+	}
+	if err != consts.ErrorsTimeout {
+		return
+	}
+	metrics.Timeouts.Inc(turn)
+	if player.IncrTimeouts() >= consts.AutopilotTimeouts {
+		player.SetAuto(true)
+		database.Broadcast(player.RoomID, fmt.Sprintf("%s timed out %d times, autopilot takes over, input anything to take back control\n", player.Name, consts.AutopilotTimeouts))
+	}
+}
+
 func handlePlay(player *database.Player, game *database.Game) error {
 	master := player.ID == game.LastPlayer || game.LastPlayer == 0
-	database.Broadcast(player.RoomID, fmt.Sprintf("%s turn to play\n", player.Name))
+	game.Turn = player.ID
+	render.Publish(player.RoomID, render.NewTurnEvent(player, game.PlayTimeOut[player.ID], fmt.Sprintf("%s turn to play\n", player.Name)))
 	if master && game.Properties[consts.RoomPropsSkill] {
 		sk := skill.Skills[consts.SkillID(game.Skills[player.ID])]
-		database.Broadcast(player.RoomID, fmt.Sprintf("%s \n", sk.Desc(player)))
+		render.Publish(player.RoomID, render.NewSkillEvent(player, sk.Name(), fmt.Sprintf("%s \n", sk.Desc(player))))
 		sk.Apply(player, game)
+		render.Publish(player.RoomID, render.NewHandsEvent(game.Hands(), ""))
 	}
 	return playing(player, game, master, game.PlayTimes[player.ID])
 }
 
+// Resume resends the hand, the last play and the current turn to a reconnected player.
+func Resume(player *database.Player) error {
+	room := database.GetRoom(player.RoomID)
+	if room == nil || room.Game == nil {
+		return consts.ErrorsExist
+	}
+	game := room.Game
+	buf := bytes.Buffer{}
+	buf.WriteString("Reconnected to the game!\n")
+	if game.Properties[consts.RoomPropsLaiZi] {
+		buf.WriteString(fmt.Sprintf("Universals: %s %s\n", poker.GetDesc(game.Universals[0]), poker.GetDesc(game.Universals[1])))
+	}
+	if game.IsDuel() {
+		buf.WriteString(fmt.Sprintf("Concessions: %d\n", game.Concessions))
+	}
+	buf.WriteString(openHand(game, player.ID))
+	buf.WriteString(fmt.Sprintf("Your pokers: %s\n", game.Pokers[player.ID].String()))
+	if game.LastPlayer != 0 && len(game.LastPokers) > 0 {
+		buf.WriteString(fmt.Sprintf("Last player: %s (%s), played: %s\n", database.GetPlayer(game.LastPlayer).Name, game.Team(game.LastPlayer), game.LastPokers.String()))
+	}
+	if turn := database.GetPlayer(game.Turn); turn != nil {
+		buf.WriteString(fmt.Sprintf("Now it's %s's turn\n", turn.Name))
+	}
+	return render.Send(player, render.NewHandEvent(game.Pokers[player.ID], buf.String()))
+}
+
+// randomUniversals picks the two LaiZi keys, never the jokers nor the keys taken out of the duel deck.
+func randomUniversals(duel bool) []int {
+	exclude := []int{14, 15}
+	if duel {
+		for key := range duelKeys {
+			exclude = append(exclude, key)
+		}
+	}
+	first := poker.Random(exclude...)
+	return []int{first, poker.Random(append(exclude, first)...)}
+}
+
 func InitGame(room *database.Room, rules poker.Rules) (*database.Game, error) {
-	distributes, decks := poker.Distribute(room.Players, room.GetProperty(consts.RoomPropsDotShuffle), rules)
 	players := make([]int64, 0)
 	roomPlayers := database.RoomPlayers(room.ID)
 	for playerId := range roomPlayers {
 		players = append(players, playerId)
 	}
-	firstOaa := poker.Random(14, 15)
-	lastOaa := poker.Random(14, 15, firstOaa)
+	distributes, removed, decks := distribute(len(players), room.GetProperty(consts.RoomPropsDotShuffle), rules)
+	universals := randomUniversals(removed != nil)
 	states := map[int64]chan int{}
 	groups := map[int64]int{}
 	pokers := map[int64]modelx.Pokers{}
 	skills := map[int64]int{}
 	playTimes := map[int64]int{}
 	playTimeout := map[int64]time.Duration{}
+	plays := map[int64]int{}
 	mnemonic := map[int]int{
 		14: decks,
 		15: decks,
@@ -352,6 +540,11 @@ func InitGame(room *database.Room, rules poker.Rules) (*database.Game, error) {
 	for i := 1; i <= 13; i++ {
 		mnemonic[i] = 4 * decks
 	}
+	if removed != nil {
+		for key := range duelKeys {
+			mnemonic[key] = 0
+		}
+	}
 	rand.Seed(time.Now().UnixNano())
 	for i := range players {
 		states[players[i]] = make(chan int, 1)
@@ -359,31 +552,40 @@ func InitGame(room *database.Room, rules poker.Rules) (*database.Game, error) {
 		pokers[players[i]] = distributes[i]
 		skills[players[i]] = rand.Intn(len(skill.Skills))
 		playTimes[players[i]] = 1
-		playTimeout[players[i]] = consts.PlayTimeout
+		playTimeout[players[i]] = config.Get().PlayTimeoutOf(room.Type)
 	}
 	rand.Seed(time.Now().UnixNano())
 	states[players[rand.Intn(len(states))]] <- stateRob
-	return &database.Game{
+	game := &database.Game{
+		Type:        room.Type,
 		States:      states,
 		Players:     players,
 		Groups:      groups,
 		Pokers:      pokers,
 		Additional:  distributes[len(distributes)-1],
+		Removed:     removed,
+		Base:        consts.BaseScore,
 		Multiple:    1,
-		Universals:  []int{firstOaa, lastOaa},
+		Universals:  universals,
 		Mnemonic:    mnemonic,
 		Decks:       decks,
 		Properties:  room.GetProperties(),
 		Skills:      skills,
 		PlayTimes:   playTimes,
 		PlayTimeOut: playTimeout,
+		Plays:       plays,
 		Rules:       rules,
 		Discards:    modelx.Pokers{},
-	}, nil
+		StartTime:   time.Now(),
+	}
+	Observe(room.ID, game)
+	deal(room.ID, game)
+	metrics.GamesStarted.Inc(consts.GameTypes[room.Type])
+	return game, nil
 }
 
-func resetGame(game *database.Game) error {
-	distributes, decks := poker.Distribute(len(game.Players), game.Properties[consts.RoomPropsDotShuffle], game.Rules)
+func resetGame(roomId int64, game *database.Game) error {
+	distributes, removed, decks := distribute(len(game.Players), game.Properties[consts.RoomPropsDotShuffle], game.Rules)
 	if len(distributes) != len(game.Players)+1 {
 		return consts.ErrorsGamePlayersInvalid
 	}
@@ -391,43 +593,78 @@ func resetGame(game *database.Game) error {
 	skills := map[int64]int{}
 	playTimes := map[int64]int{}
 	playTimeout := map[int64]time.Duration{}
-	firstOaa := poker.Random(14, 15)
-	lastOaa := poker.Random(14, 15, firstOaa)
+	universals := randomUniversals(removed != nil)
 	rand.Seed(time.Now().UnixNano())
 	for i := range players {
 		game.Pokers[players[i]] = distributes[i]
 		skills[players[i]] = rand.Intn(len(skill.Skills))
 		playTimes[players[i]] = 1
-		playTimeout[players[i]] = consts.PlayTimeout
+		playTimeout[players[i]] = config.Get().PlayTimeoutOf(game.Type)
 	}
 	game.Groups = map[int64]int{}
 	game.FirstPlayer = 0
 	game.LastPlayer = 0
+	game.Turn = 0
 	game.FirstRob = 0
 	game.LastRob = 0
 	game.Additional = distributes[len(distributes)-1]
+	game.Removed = removed
+	game.Concessions = 0
+	game.OpenHand = false
 	game.FinalRob = false
+	game.Base = consts.BaseScore
 	game.Multiple = 1
-	game.Universals = []int{firstOaa, lastOaa}
+	game.Bombs = 0
+	game.Rockets = 0
+	game.Plays = map[int64]int{}
+	game.Universals = universals
 	game.Decks = decks
 	game.Skills = skills
 	game.PlayTimes = playTimes
 	game.PlayTimeOut = playTimeout
 	game.Discards = modelx.Pokers{}
+	game.StartTime = time.Now()
+	game.ClearEvents()
+	deal(roomId, game)
 	return nil
 }
 
-func viewGame(game *database.Game, currPlayer *database.Player) {
+// deal publishes the hands, the log of a redealt hand only keeps the last deal.
+func deal(roomId int64, game *database.Game) {
+	var universals []int
+	if game.Properties[consts.RoomPropsLaiZi] {
+		universals = append([]int{}, game.Universals...)
+	}
+	render.Publish(roomId, render.NewDealEvent(game.Hands(), append(modelx.Pokers{}, game.Additional...), append(modelx.Pokers(nil), game.Removed...), universals, ""))
+}
+
+// Table renders the seats of the game with the names, the hands are shown face up when open, as in replays,
+// and counted otherwise.
+func Table(game *database.Game, name func(id int64) string, open bool) string {
 	buf := bytes.Buffer{}
-	buf.WriteString(fmt.Sprintf("%-20s%-10s%-10s\n", "Name", "Pokers", "Identity"))
+	buf.WriteString(fmt.Sprintf("%-20s%-10s%s\n", "Name", "Identity", "Pokers"))
 	for _, id := range game.Players {
-		player := database.GetPlayer(id)
-		flag := ""
-		if id == currPlayer.ID {
-			flag = "*"
+		pokers := strconv.Itoa(len(game.Pokers[id]))
+		if open {
+			pokers = game.Pokers[id].String()
 		}
-		buf.WriteString(fmt.Sprintf("%-20s%-10d%-10s\n", player.Name+flag, len(game.Pokers[id]), game.Team(id)))
+		buf.WriteString(fmt.Sprintf("%-20s%-10s%s\n", name(id), game.Team(id), pokers))
+	}
+	buf.WriteString(fmt.Sprintf("Base: %d, multiple: %d, bombs: %d, rockets: %d\n", game.Base, game.Multiple, game.Bombs, game.Rockets))
+	if game.IsDuel() {
+		buf.WriteString(fmt.Sprintf("Concessions: %d, removed pokers: %d\n", game.Concessions, len(game.Removed)))
 	}
+	return buf.String()
+}
+
+func viewGame(game *database.Game, currPlayer *database.Player) {
+	buf := bytes.Buffer{}
+	buf.WriteString(Table(game, func(id int64) string {
+		if id == currPlayer.ID {
+			return database.GetPlayer(id).Name + "*"
+		}
+		return database.GetPlayer(id).Name
+	}, false))
 	currKeys := map[int]int{}
 	for _, currPoker := range game.Pokers[currPlayer.ID] {
 		currKeys[currPoker.Key]++
@@ -452,12 +689,3 @@ func viewGame(game *database.Game, currPlayer *database.Player) {
 	buf.WriteString("\n")
 	_ = currPlayer.WriteString(buf.String())
 }
-
-func isMax(game *database.Game, faces modelx.Faces) bool {
-	if game.Decks == 1 && len(faces.Keys) == 2 {
-		if (faces.Keys[0] == 14 && faces.Keys[1] == 15) || (faces.Keys[0] == 15 && faces.Keys[1] == 14) {
-			return true
-		}
-	}
-	return false
-}
@@ -0,0 +1,54 @@
+package game
+
+import (
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/render"
+	"github.com/ratel-online/server/robot"
+)
+
+// Observe subscribes the match recorder and the memory of robots to the events of the game,
+// both are unsubscribed once the game is over. The game is snapshotted on every event for the admin API.
+func Observe(roomId int64, game *database.Game) {
+	memory := robot.Observe(game)
+	var cancel func()
+	cancel = render.Subscribe(roomId, func(event render.Event) {
+		record(game, event)
+		memory(event)
+		game.TakeSnapshot()
+		if _, ok := event.(render.GameOverEvent); ok {
+			cancel()
+		}
+	})
+}
+
+// record turns the events into the log of the match.
+func record(game *database.Game, event render.Event) {
+	switch e := event.(type) {
+	case render.DealEvent:
+		game.Record(database.MatchEvent{Type: database.MatchEventDeal, Pokers: e.Pocket, Removed: e.Removed, Hands: e.Hands, Universals: e.Universals})
+	case render.RobEvent:
+		game.Record(database.MatchEvent{Type: database.MatchEventRob, Player: e.Player.ID, Rob: e.Rob})
+	case render.BidEvent:
+		game.Record(database.MatchEvent{Type: database.MatchEventBid, Player: e.Player.ID, Base: e.Score})
+	case render.LandlordEvent:
+		game.Record(database.MatchEvent{Type: database.MatchEventLandlord, Player: e.Player.ID, Pokers: e.Pocket})
+	case render.DoubleEvent:
+		game.Record(database.MatchEvent{Type: database.MatchEventDouble, Player: e.Player.ID, Multiple: e.Multiple, Open: e.Open})
+	case render.SkillEvent:
+		game.Record(database.MatchEvent{Type: database.MatchEventSkill, Player: e.Player.ID, Skill: e.Skill})
+	case render.HandsEvent:
+		// 技能改变了手牌，记在技能事件上
+		game.RecordHands(e.Hands)
+	case render.PlayEvent:
+		game.Record(database.MatchEvent{Type: database.MatchEventPlay, Player: e.Player.ID, Pokers: append(modelx.Pokers{}, e.Pokers...), Faces: e.Faces})
+	case render.PassEvent:
+		game.Record(database.MatchEvent{Type: database.MatchEventPass, Player: e.Player.ID})
+	case render.SettleEvent:
+		scores := map[int64]int64{}
+		for _, player := range e.Players {
+			scores[player.ID] = player.Score
+		}
+		game.Record(database.MatchEvent{Type: database.MatchEventSettle, Base: e.Base, Multiple: e.Multiple, Scores: scores})
+	}
+}
@@ -0,0 +1,165 @@
+package game
+
+import (
+	"bytes"
+	"fmt"
+	constx "github.com/ratel-online/core/consts"
+	"github.com/ratel-online/core/log"
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/metrics"
+	"github.com/ratel-online/server/rating"
+	"github.com/ratel-online/server/render"
+	"time"
+)
+
+// countBomb doubles the multiple for every bomb, rockets and joker bombs of multiple decks included.
+func countBomb(player *database.Player, game *database.Game, faces modelx.Faces) {
+	if faces.Type != constx.FacesBomb {
+		return
+	}
+	game.Multiple *= 2
+	rocket := true
+	for _, key := range faces.Keys {
+		if key != 14 && key != 15 {
+			rocket = false
+			break
+		}
+	}
+	if rocket && len(faces.Keys) == 4 {
+		game.Rockets++
+		database.Broadcast(player.RoomID, fmt.Sprintf("%s played the four jokers, multiple x2, current multiple: %d\n", player.Name, game.Multiple))
+	} else if rocket {
+		game.Rockets++
+		database.Broadcast(player.RoomID, fmt.Sprintf("%s played a rocket, multiple x2, current multiple: %d\n", player.Name, game.Multiple))
+	} else {
+		game.Bombs++
+		database.Broadcast(player.RoomID, fmt.Sprintf("%s played a bomb, multiple x2, current multiple: %d\n", player.Name, game.Multiple))
+	}
+}
+
+// countSpring doubles the multiple on spring, when the landlord wins before any peasant played,
+// or on anti-spring, when the peasants win and the landlord played only once.
+func countSpring(player *database.Player, game *database.Game, winner int64) {
+	if game.Properties[consts.RoomPropsSkill] {
+		return
+	}
+	spring, antiSpring := game.IsLandlord(winner), !game.IsLandlord(winner)
+	for _, id := range game.Players {
+		if game.IsLandlord(id) {
+			antiSpring = antiSpring && game.Plays[id] == 1
+		} else {
+			spring = spring && game.Plays[id] == 0
+		}
+	}
+	if spring {
+		game.Multiple *= 2
+		database.Broadcast(player.RoomID, fmt.Sprintf("Spring! multiple x2, current multiple: %d\n", game.Multiple))
+	} else if antiSpring {
+		game.Multiple *= 2
+		database.Broadcast(player.RoomID, fmt.Sprintf("Anti-spring! multiple x2, current multiple: %d\n", game.Multiple))
+	}
+}
+
+// settle computes the scores of a finished hand. The unit is the base score times the multiple,
+// every loser pays one unit to every winner: the landlord wins or loses one unit per peasant,
+// and in skill mode, where everybody is its own team, the winner collects one unit from each player.
+func settle(room *database.Room, game *database.Game, winner int64) *database.Match {
+	if game.IsDuel() {
+		return settleDuel(room, game, winner)
+	}
+	unit := int64(game.Base * game.Multiple)
+	winners, losers := make([]int64, 0), make([]int64, 0)
+	for _, id := range game.Players {
+		if game.IsTeammate(id, winner) {
+			winners = append(winners, id)
+		} else {
+			losers = append(losers, id)
+		}
+	}
+	scores := map[int64]int64{}
+	for _, w := range winners {
+		for _, l := range losers {
+			scores[w] += unit
+			scores[l] -= unit
+		}
+	}
+	header := fmt.Sprintf("Settlement, base: %d, multiple: %d, bombs: %d, rockets: %d\n", game.Base, game.Multiple, game.Bombs, game.Rockets)
+	return saveMatch(room, game, winner, scores, header)
+}
+
+// settleDuel computes the scores of the two-player game, the loser pays one unit to the winner
+// whether the peasant emptied the hand or got within the concessions, the removed pokers are revealed.
+func settleDuel(room *database.Room, game *database.Game, winner int64) *database.Match {
+	unit := int64(game.Base * game.Multiple)
+	scores := map[int64]int64{}
+	for _, id := range game.Players {
+		if id == winner {
+			scores[id] += unit
+		} else {
+			scores[id] -= unit
+		}
+	}
+	header := fmt.Sprintf("Settlement, base: %d, multiple: %d, bombs: %d, rockets: %d, concessions: %d\nRemoved pokers: %s\n",
+		game.Base, game.Multiple, game.Bombs, game.Rockets, game.Concessions, game.Removed.String())
+	return saveMatch(room, game, winner, scores, header)
+}
+
+// saveMatch applies the scores and the ratings, publishes the settlement and saves the match.
+func saveMatch(room *database.Room, game *database.Game, winner int64, scores map[int64]int64, header string) *database.Match {
+	seats := make([]rating.Seat, 0, len(game.Players))
+	for _, id := range game.Players {
+		seats = append(seats, rating.Seat{
+			ID:       id,
+			Rating:   database.GetRating(id, room.Type),
+			Landlord: !game.Properties[consts.RoomPropsSkill] && game.IsLandlord(id),
+			Winner:   game.IsTeammate(id, winner),
+			Robot:    database.GetPlayer(id).IsRobot(),
+		})
+	}
+	deltas, rated := rating.Update(seats), rating.Rated(seats)
+	metrics.GamesFinished.Inc(consts.GameTypes[room.Type])
+	metrics.HandDuration.Since(game.StartTime, consts.GameTypes[room.Type])
+
+	match := &database.Match{
+		Version:    database.MatchVersion,
+		RoomID:     room.ID,
+		Type:       room.Type,
+		Multiple:   game.Multiple,
+		Properties: game.Properties,
+		StartTime:  game.StartTime,
+		EndTime:    time.Now(),
+	}
+	buf := bytes.Buffer{}
+	buf.WriteString(header)
+	buf.WriteString(fmt.Sprintf("%-20s%-10s%-10s%-10s%-10s\n", "Name", "Identity", "Score", "Total", "Rating"))
+	for i, id := range game.Players {
+		player := database.GetPlayer(id)
+		player.Score += scores[id]
+		if err := database.SavePlayer(player); err != nil {
+			log.Error(err)
+		}
+		newRating := seats[i].Rating + deltas[id]
+		if rated {
+			if err := database.SetRating(id, room.Type, newRating); err != nil {
+				log.Error(err)
+			}
+		}
+		match.Players = append(match.Players, database.MatchPlayer{
+			ID:       id,
+			Name:     player.Name,
+			Landlord: seats[i].Landlord,
+			Winner:   scores[id] > 0,
+			Score:    scores[id],
+			Rating:   newRating,
+		})
+		buf.WriteString(fmt.Sprintf("%-20s%-10s%-10s%-10d%-10s\n", player.Name, game.Team(id), fmt.Sprintf("%+d", scores[id]), player.Score, fmt.Sprintf("%d(%+d)", newRating, deltas[id])))
+	}
+	render.Publish(room.ID, render.NewSettleEvent(match, game.Base, buf.String()))
+	match.Events = game.Events
+	if err := database.GetStore().SaveMatch(match); err != nil {
+		log.Error(err)
+	}
+	return match
+}
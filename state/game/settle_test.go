@@ -0,0 +1,33 @@
+package game
+
+import (
+	constx "github.com/ratel-online/core/consts"
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/server/database"
+	"testing"
+)
+
+func TestCountBomb(t *testing.T) {
+	tests := []struct {
+		faces   modelx.Faces
+		bombs   int
+		rockets int
+	}{
+		{faces: modelx.Faces{Type: constx.FacesSingle, Keys: []int{14}}},
+		{faces: modelx.Faces{Type: constx.FacesBomb, Keys: []int{5, 5, 5, 5}}, bombs: 1},
+		{faces: modelx.Faces{Type: constx.FacesBomb, Keys: []int{14, 15}}, rockets: 1},
+		// 多副牌的四王炸
+		{faces: modelx.Faces{Type: constx.FacesBomb, Keys: []int{14, 14, 15, 15}}, rockets: 1},
+	}
+	for _, tt := range tests {
+		game := &database.Game{Multiple: 1}
+		countBomb(&database.Player{}, game, tt.faces)
+		multiple := 1
+		if tt.faces.Type == constx.FacesBomb {
+			multiple = 2
+		}
+		if game.Bombs != tt.bombs || game.Rockets != tt.rockets || game.Multiple != multiple {
+			t.Fatalf("faces %v: unexpected bombs %d rockets %d multiple %d", tt.faces.Keys, game.Bombs, game.Rockets, game.Multiple)
+		}
+	}
+}
@@ -1,29 +1,40 @@
 package state
 
 import (
-	"bytes"
 	"github.com/ratel-online/server/consts"
 	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/render"
+	"strconv"
+	"strings"
 )
 
 type home struct{}
 
 func (*home) Next(player *database.Player) (consts.StateID, error) {
-	buf := bytes.Buffer{}
-	buf.WriteString("1.Join\n")
-	buf.WriteString("2.New\n")
-	err := player.WriteString(buf.String())
+	err := render.HomeOptions(player)
 	if err != nil {
 		return 0, player.WriteError(err)
 	}
-	selected, err := player.AskForInt()
+	signal, err := player.AskForString()
 	if err != nil {
 		return 0, player.WriteError(err)
 	}
+	signal = strings.ToLower(strings.TrimSpace(signal))
+	if render.Query(player, signal, consts.GameTypeClassic) {
+		return consts.StateHome, nil
+	}
+	selected, err := strconv.Atoi(signal)
+	if err != nil {
+		return 0, player.WriteError(consts.ErrorsInputInvalid)
+	}
 	if selected == 1 {
 		return consts.StateJoin, nil
 	} else if selected == 2 {
 		return consts.StateNew, nil
+	} else if selected == 3 {
+		return consts.StateMatch, nil
+	} else if selected == 4 {
+		return consts.StateReplay, nil
 	}
 	return 0, player.WriteError(consts.ErrorsInputInvalid)
 }
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"github.com/ratel-online/server/consts"
 	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/render"
 	"strconv"
+	"strings"
 )
 
 type join struct{}
@@ -13,13 +15,20 @@ type join struct{}
 func (s *join) Next(player *database.Player) (consts.StateID, error) {
 	buf := bytes.Buffer{}
 	rooms := database.GetRooms()
+	listed := make([]*database.Room, 0)
+	running := make([]string, 0)
 	buf.WriteString(fmt.Sprintf("%-10s%-10s%-10s%-10s\n", "ID", "Type", "Players", "State"))
 	for _, room := range rooms {
 
-		// 游戏进行中或者已经满了就不展示在房间列表里面了
-		if room.Players >= room.MaxPlayer || room.State == consts.RoomStateRunning {
+		// 游戏进行中或者已经满了就不展示在房间列表里面了，进行中的房间可以观战
+		if room.State == consts.RoomStateRunning {
+			running = append(running, strconv.FormatInt(room.ID, 10))
 			continue
 		}
+		if room.Players >= room.MaxPlayer {
+			continue
+		}
+		listed = append(listed, room)
 
 		// 密码房id前面加星号
 		if room.Password != "" {
@@ -28,7 +37,10 @@ func (s *join) Next(player *database.Player) (consts.StateID, error) {
 			buf.WriteString(fmt.Sprintf("%-10d%-10s%-10d%-10s\n", room.ID, consts.GameTypes[room.Type], room.Players, consts.RoomStates[room.State]))
 		}
 	}
-	err := player.WriteString(buf.String())
+	if len(running) > 0 {
+		buf.WriteString(fmt.Sprintf("Running rooms: %s, input watch <id> to watch\n", strings.Join(running, " ")))
+	}
+	err := render.RoomList(player, listed, buf.String())
 	if err != nil {
 		return 0, player.WriteError(err)
 	}
@@ -42,6 +54,11 @@ func (s *join) Next(player *database.Player) (consts.StateID, error) {
 	if isLs(signal) {
 		return consts.StateJoin, nil
 	}
+	watching := false
+	if tags := strings.Fields(strings.ToLower(signal)); len(tags) == 2 && tags[0] == "watch" {
+		watching = true
+		signal = tags[1]
+	}
 	roomId, err := strconv.ParseInt(signal, 10, 64)
 	if err != nil {
 		return 0, player.WriteError(consts.ErrorsRoomInvalid)
@@ -79,11 +96,19 @@ func (s *join) Next(player *database.Player) (consts.StateID, error) {
 
 	}
 
+	if watching {
+		err = database.WatchRoom(roomId, player.ID, room.Password)
+		if err != nil {
+			return 0, player.WriteError(err)
+		}
+		database.Broadcast(roomId, fmt.Sprintf("%s is watching the room, %d spectators\n", player.Name, room.Spectators))
+		return consts.StateWatch, nil
+	}
 	err = database.JoinRoom(roomId, player.ID, room.Password)
 	if err != nil {
 		return 0, player.WriteError(err)
 	}
-	database.Broadcast(roomId, fmt.Sprintf("%s joined room! room current has %d players\n", player.Name, room.Players))
+	render.Join(player, room)
 	return consts.StateWaiting, nil
 }
 
@@ -0,0 +1,128 @@
+package state
+
+import (
+	"fmt"
+	"github.com/ratel-online/core/log"
+	"github.com/ratel-online/core/util/async"
+	"github.com/ratel-online/server/config"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type match struct{}
+
+func init() {
+	async.Async(func() {
+		for {
+			time.Sleep(time.Second)
+			if !database.ShuttingDown() {
+				database.Matching(startMatch)
+			}
+		}
+	})
+}
+
+func (s *match) Next(player *database.Player) (consts.StateID, error) {
+	if database.ShuttingDown() {
+		_ = player.WriteError(consts.ErrorsServerShuttingDown)
+		return consts.StateHome, nil
+	}
+	gameType, err := askGameType(player)
+	if err != nil {
+		return 0, err
+	}
+	playerNum, err := askPlayerNum(player, gameType)
+	if err != nil {
+		return 0, err
+	}
+	database.Enqueue(player, gameType, playerNum)
+	err = player.WriteString(fmt.Sprintf("Matching %d players %s game, your rating: %d, input e to cancel\n", playerNum, consts.GameTypes[gameType], database.GetRating(player.ID, gameType)))
+	if err != nil {
+		database.Dequeue(player.ID)
+		return 0, player.WriteError(err)
+	}
+	player.StartTransaction()
+	defer player.StopTransaction()
+	for {
+		_, err = player.AskForStringWithoutTransaction(time.Second)
+		if room := database.GetRoom(player.RoomID); room != nil && room.State == consts.RoomStateRunning {
+			return gameStateOf(room), nil
+		}
+		if err == consts.ErrorsTimeout && !database.Queued(player.ID) {
+			// 匹配时未能入座，已被移出队列
+			_ = player.WriteError(consts.ErrorsMatchFailed)
+			return consts.StateHome, nil
+		}
+		if err != nil && err != consts.ErrorsTimeout {
+			// 已经匹配成功的玩家不能再退出，直接进入游戏
+			if !database.Dequeue(player.ID) && player.RoomID != 0 {
+				return gameStateOf(database.GetRoom(player.RoomID)), nil
+			}
+			return 0, err
+		}
+	}
+}
+
+func (*match) Exit(player *database.Player) consts.StateID {
+	database.Dequeue(player.ID)
+	return consts.StateHome
+}
+
+// 询问匹配人数
+func askPlayerNum(player *database.Player, gameType int) (int, error) {
+	min, max := config.Get().MinPlayersOf(gameType), config.Get().MaxPlayersOf(gameType)
+	err := player.WriteString(fmt.Sprintf("Please input players number (%d~%d), default %d\n", min, max, config.Get().MatchPlayersOf(gameType)))
+	if err != nil {
+		return 0, player.WriteError(err)
+	}
+	signal, err := player.AskForString()
+	if err != nil {
+		return 0, player.WriteError(err)
+	}
+	signal = strings.TrimSpace(signal)
+	if signal == "" {
+		return config.Get().MatchPlayersOf(gameType), nil
+	}
+	playerNum, err := strconv.Atoi(signal)
+	if err != nil || playerNum < min || playerNum > max {
+		return 0, player.WriteError(consts.ErrorsInputInvalid)
+	}
+	return playerNum, nil
+}
+
+// startMatch seats the matched players in a new room and starts the game right away. If anyone
+// can't be seated the room is dissolved and the players who failed are returned, the others stay queued.
+func startMatch(gameType int, players []*database.Player) []*database.Player {
+	room := database.CreateRoom(players[0].ID, "", len(players))
+	room.Type = gameType
+	applyGameType(room)
+	failed := make([]*database.Player, 0)
+	for _, player := range players {
+		if err := database.JoinRoom(room.ID, player.ID, ""); err != nil {
+			log.Errorf("player %s failed to join matched room %d: %v\n", player, room.ID, err)
+			failed = append(failed, player)
+		}
+	}
+	if len(failed) == 0 {
+		room.Lock()
+		game, err := initGame(room)
+		if err == nil {
+			room.Game = game
+			room.State = consts.RoomStateRunning
+			room.Unlock()
+			log.Infof("room %d matched %d players for %s game\n", room.ID, len(players), consts.GameTypes[gameType])
+			return nil
+		}
+		room.Unlock()
+		log.Error(err)
+		failed = players
+	}
+	// 最后一个玩家离开时房间随之解散
+	for _, player := range players {
+		database.LeaveRoom(room.ID, player.ID)
+	}
+	return failed
+}
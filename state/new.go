@@ -1,10 +1,11 @@
 package state
 
 import (
-	"bytes"
 	"fmt"
+	"github.com/ratel-online/server/config"
 	"github.com/ratel-online/server/consts"
 	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/render"
 )
 
 type new struct{}
@@ -17,7 +18,7 @@ func (*new) Next(player *database.Player) (consts.StateID, error) {
 	}
 
 	// 创建房间资源
-	room := database.CreateRoom(player.ID, "", consts.MaxPlayers)
+	room := database.CreateRoom(player.ID, "", config.Get().MaxPlayersOf(gameType))
 	room.Type = gameType
 	err = player.WriteString(fmt.Sprintf("Create room successful, id : %d\n", room.ID))
 	if err != nil {
@@ -36,12 +37,7 @@ func (*new) Exit(player *database.Player) consts.StateID {
 
 // 询问游戏类型
 func askGameType(player *database.Player) (gameType int, err error) {
-	buf := bytes.Buffer{}
-	buf.WriteString("Please select game type\n")
-	for _, id := range consts.GameTypesIds {
-		buf.WriteString(fmt.Sprintf("%d.%s\n", id, consts.GameTypes[id]))
-	}
-	err = player.WriteString(buf.String())
+	err = render.GameTypeOptions(player)
 	if err != nil {
 		return 0, player.WriteError(err)
 	}
@@ -51,7 +47,7 @@ func askGameType(player *database.Player) (gameType int, err error) {
 	}
 
 	// 游戏类型输入非法
-	if _, ok := consts.GameTypes[gameType]; !ok {
+	if !database.IsGameTypeOffered(gameType) {
 		return 0, player.WriteError(consts.ErrorsGameTypeInvalid)
 	}
 	return
@@ -0,0 +1,290 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	constx "github.com/ratel-online/core/consts"
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/util/poker"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	gamex "github.com/ratel-online/server/state/game"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type replay struct{}
+
+func (s *replay) Next(player *database.Player) (consts.StateID, error) {
+	match, err := askForMatch(player)
+	if err != nil {
+		return 0, err
+	}
+	step, auto, speed := 0, false, consts.ReplaySpeed
+	for {
+		_ = player.WriteString(viewReplay(match, step))
+		var signal string
+		if auto {
+			signal, err = player.AskForString(speed)
+			if err == consts.ErrorsTimeout {
+				if step < len(match.Events)-1 {
+					step++
+				} else {
+					auto = false
+				}
+				continue
+			}
+			// 自动播放时输入任意内容暂停
+			auto = false
+		} else {
+			signal, err = player.AskForString()
+		}
+		if err != nil {
+			return 0, player.WriteError(err)
+		}
+		tags := strings.Fields(strings.ToLower(signal))
+		if len(tags) == 0 {
+			continue
+		}
+		switch tags[0] {
+		case "n":
+			if step < len(match.Events)-1 {
+				step++
+			}
+		case "p":
+			if step > 0 {
+				step--
+			}
+		case "a":
+			auto = true
+			if len(tags) > 1 {
+				seconds, err := strconv.Atoi(tags[1])
+				if err != nil || seconds <= 0 {
+					auto = false
+					_ = player.WriteError(consts.ErrorsInputInvalid)
+					break
+				}
+				speed = time.Duration(seconds) * time.Second
+			}
+		default:
+			_ = player.WriteError(consts.ErrorsInputInvalid)
+		}
+	}
+}
+
+func (*replay) Exit(player *database.Player) consts.StateID {
+	return consts.StateHome
+}
+
+// askForMatch lists the latest matches of the player and asks for the id of the one to replay,
+// any recorded match can be replayed once it is over.
+func askForMatch(player *database.Player) (*database.Match, error) {
+	matches, err := database.GetStore().GetMatches(player.ID, consts.ReplayMatches)
+	if err != nil {
+		return nil, player.WriteError(err)
+	}
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("%-10s%-10s%-22s%-10s\n", "ID", "Type", "Time", "Result"))
+	for _, match := range matches {
+		result := "lose"
+		for _, p := range match.Players {
+			if p.ID == player.ID && p.Winner {
+				result = "win"
+			}
+		}
+		if match.Void {
+			result = "void"
+		}
+		buf.WriteString(fmt.Sprintf("%-10d%-10s%-22s%-10s\n", match.ID, consts.GameTypes[match.Type], match.StartTime.Format("2006-01-02 15:04:05"), result))
+	}
+	buf.WriteString("Please input the id of the game to replay\n")
+	if err = player.WriteString(buf.String()); err != nil {
+		return nil, player.WriteError(err)
+	}
+	id, err := player.AskForInt()
+	if err != nil {
+		return nil, player.WriteError(consts.ErrorsInputInvalid)
+	}
+	match, err := database.GetStore().GetMatch(int64(id))
+	if err != nil {
+		return nil, player.WriteError(err)
+	}
+	if match == nil || len(match.Events) == 0 {
+		return nil, player.WriteError(consts.ErrorsMatchNotExists)
+	}
+	_ = player.WriteString("Replay controls: n next, p previous, a [seconds] auto play, input anything to pause, e exit\n")
+	return match, nil
+}
+
+// replayGame is the table of a recorded hand at some step.
+type replayGame struct {
+	database.Game
+	Names map[int64]string
+}
+
+// replayTo rebuilds the table by applying the events of the match up to the step.
+func replayTo(match *database.Match, step int) *replayGame {
+	game := &replayGame{
+		Game: database.Game{
+			Type:       match.Type,
+			Groups:     map[int64]int{},
+			Pokers:     map[int64]modelx.Pokers{},
+			Properties: match.Properties,
+		},
+		Names: map[int64]string{},
+	}
+	for _, p := range match.Players {
+		game.Players = append(game.Players, p.ID)
+		game.Names[p.ID] = p.Name
+	}
+	for _, event := range match.Events[:step+1] {
+		switch event.Type {
+		case database.MatchEventDeal:
+			game.Groups = map[int64]int{}
+			game.Base = consts.BaseScore
+			game.Multiple = 1
+			game.Bombs, game.Rockets = 0, 0
+			game.Universals = event.Universals
+			game.Additional = event.Pokers
+			game.Removed = event.Removed
+			game.LastPokers = nil
+			copyHands(game, event.Hands)
+			if len(game.Universals) > 0 {
+				for _, pokers := range game.Pokers {
+					pokers.SetOaa(game.Universals[0])
+				}
+			}
+		case database.MatchEventRob:
+			if event.Rob {
+				game.Multiple *= 2
+			}
+		case database.MatchEventBid:
+			if event.Base > 0 {
+				game.Base = event.Base
+			}
+		case database.MatchEventLandlord:
+			game.Groups[event.Player] = 1
+			game.Pokers[event.Player] = append(game.Pokers[event.Player], event.Pokers...)
+			for _, pokers := range game.Pokers {
+				pokers.SetOaa(game.Universals...)
+			}
+		case database.MatchEventDouble:
+			game.Multiple *= event.Multiple
+		case database.MatchEventSkill:
+			copyHands(game, event.Hands)
+		case database.MatchEventPlay:
+			game.Pokers[event.Player] = removePokers(game.Pokers[event.Player], event.Pokers)
+			game.LastPlayer = event.Player
+			game.LastPokers = event.Pokers
+			if event.Faces != nil && event.Faces.Type == constx.FacesBomb {
+				game.Multiple *= 2
+				if isRocket(event.Faces.Keys) {
+					game.Rockets++
+				} else {
+					game.Bombs++
+				}
+			}
+		case database.MatchEventSettle:
+			game.Base = event.Base
+			game.Multiple = event.Multiple
+		}
+	}
+	for _, pokers := range game.Pokers {
+		pokers.SortByOaaValue()
+	}
+	if game.Properties[consts.RoomPropsSkill] {
+		for i, id := range game.Players {
+			game.Groups[id] = i
+		}
+	}
+	return game
+}
+
+// isRocket reports whether the bomb is made of jokers only.
+func isRocket(keys []int) bool {
+	for _, key := range keys {
+		if key != 14 && key != 15 {
+			return false
+		}
+	}
+	return true
+}
+
+func copyHands(game *replayGame, hands map[int64]modelx.Pokers) {
+	for id, pokers := range hands {
+		game.Pokers[id] = append(modelx.Pokers{}, pokers...)
+	}
+}
+
+// removePokers takes the played pokers out of the hand, universals stand in for the key they were played as.
+func removePokers(hand modelx.Pokers, played modelx.Pokers) modelx.Pokers {
+	rest := append(modelx.Pokers{}, hand...)
+	for _, p := range played {
+		for i := range rest {
+			if (p.Oaa && rest[i].Oaa) || (!p.Oaa && !rest[i].Oaa && rest[i].Key == p.Key) {
+				rest = append(rest[:i], rest[i+1:]...)
+				break
+			}
+		}
+	}
+	return rest
+}
+
+func viewReplay(match *database.Match, step int) string {
+	game := replayTo(match, step)
+	event := match.Events[step]
+	name := game.Names[event.Player]
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("\nGame %d, step %d/%d, +%ds\n", match.ID, step+1, len(match.Events), int(event.Time.Sub(match.Events[0].Time).Seconds())))
+	switch event.Type {
+	case database.MatchEventDeal:
+		buf.WriteString(fmt.Sprintf("Deal, pocket: %s", event.Pokers.String()))
+		if len(event.Removed) > 0 {
+			buf.WriteString(" removed: " + event.Removed.String())
+		}
+		for _, key := range event.Universals {
+			buf.WriteString(" universal: " + poker.GetDesc(key))
+		}
+		buf.WriteString("\n")
+	case database.MatchEventRob:
+		if event.Rob {
+			buf.WriteString(fmt.Sprintf("%s rob\n", name))
+		} else {
+			buf.WriteString(fmt.Sprintf("%s don't rob\n", name))
+		}
+	case database.MatchEventBid:
+		if event.Base > 0 {
+			buf.WriteString(fmt.Sprintf("%s bid %d\n", name, event.Base))
+		} else {
+			buf.WriteString(fmt.Sprintf("%s don't bid\n", name))
+		}
+	case database.MatchEventLandlord:
+		buf.WriteString(fmt.Sprintf("%s became landlord, got pokers: %s\n", name, event.Pokers.String()))
+	case database.MatchEventDouble:
+		if event.Open {
+			buf.WriteString(fmt.Sprintf("%s opened the hand, multiple x%d\n", name, event.Multiple))
+		} else if event.Multiple > 2 {
+			buf.WriteString(fmt.Sprintf("%s doubled super, multiple x%d\n", name, event.Multiple))
+		} else if event.Multiple > 1 {
+			buf.WriteString(fmt.Sprintf("%s doubled, multiple x%d\n", name, event.Multiple))
+		} else {
+			buf.WriteString(fmt.Sprintf("%s don't double\n", name))
+		}
+	case database.MatchEventSkill:
+		buf.WriteString(fmt.Sprintf("%s triggered skill %s\n", name, event.Skill))
+	case database.MatchEventPlay:
+		buf.WriteString(fmt.Sprintf("%s played %s\n", name, event.Pokers.OaaString()))
+	case database.MatchEventPass:
+		buf.WriteString(fmt.Sprintf("%s passed\n", name))
+	case database.MatchEventSettle:
+		buf.WriteString(fmt.Sprintf("Settlement, base: %d, multiple: %d\n", event.Base, event.Multiple))
+		for _, id := range game.Players {
+			buf.WriteString(fmt.Sprintf("%s: %+d\n", game.Names[id], event.Scores[id]))
+		}
+	}
+	buf.WriteString(gamex.Table(&game.Game, func(id int64) string {
+		return game.Names[id]
+	}, true))
+	return buf.String()
+}
@@ -0,0 +1,120 @@
+package state
+
+import (
+	"fmt"
+	constx "github.com/ratel-online/core/consts"
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/util/poker"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// recordedMatch is a short landlord hand: 1 robs, becomes landlord and plays a single and the rocket.
+func recordedMatch() *database.Match {
+	return &database.Match{
+		ID:         1,
+		Type:       consts.GameTypeClassic,
+		Properties: map[string]bool{},
+		Players: []database.MatchPlayer{
+			{ID: 1, Name: "nico"},
+			{ID: 2, Name: "maki"},
+			{ID: 3, Name: "umi"},
+		},
+		Events: []database.MatchEvent{
+			{Type: database.MatchEventDeal, Pokers: poker.GetPokers(10), Hands: map[int64]modelx.Pokers{
+				1: poker.GetPokers(3, 4, 14, 15),
+				2: poker.GetPokers(6, 6, 7),
+				3: poker.GetPokers(8, 9),
+			}},
+			{Type: database.MatchEventRob, Player: 1, Rob: true},
+			{Type: database.MatchEventLandlord, Player: 1, Pokers: poker.GetPokers(10)},
+			{Type: database.MatchEventPlay, Player: 1, Pokers: poker.GetPokers(3), Faces: &modelx.Faces{Keys: []int{3}, Type: constx.FacesSingle}},
+			{Type: database.MatchEventPass, Player: 2},
+			{Type: database.MatchEventPlay, Player: 1, Pokers: poker.GetPokers(14, 15), Faces: &modelx.Faces{Keys: []int{14, 15}, Type: constx.FacesBomb}},
+			{Type: database.MatchEventSettle, Base: 1, Multiple: 4, Scores: map[int64]int64{1: 8, 2: -4, 3: -4}},
+		},
+	}
+}
+
+func keysOf(pokers modelx.Pokers) []int {
+	keys := make([]int, 0, len(pokers))
+	for _, p := range pokers {
+		keys = append(keys, p.Key)
+	}
+	sort.Ints(keys)
+	return keys
+}
+
+func TestReplayTo(t *testing.T) {
+	tests := []struct {
+		step     int
+		hands    map[int64][]int
+		landlord bool
+		multiple int
+		rockets  int
+	}{
+		{step: 0, hands: map[int64][]int{1: {3, 4, 14, 15}, 2: {6, 6, 7}, 3: {8, 9}}, multiple: 1},
+		{step: 2, hands: map[int64][]int{1: {3, 4, 10, 14, 15}, 2: {6, 6, 7}}, landlord: true, multiple: 2},
+		{step: 3, hands: map[int64][]int{1: {4, 10, 14, 15}}, landlord: true, multiple: 2},
+		{step: 5, hands: map[int64][]int{1: {4, 10}, 3: {8, 9}}, landlord: true, multiple: 4, rockets: 1},
+	}
+	match := recordedMatch()
+	for _, tt := range tests {
+		game := replayTo(match, tt.step)
+		for id, keys := range tt.hands {
+			if got := keysOf(game.Pokers[id]); fmt.Sprint(got) != fmt.Sprint(keys) {
+				t.Fatalf("step %d: expected %d to hold %v, got %v", tt.step, id, keys, got)
+			}
+		}
+		if game.IsLandlord(1) != tt.landlord || game.Multiple != tt.multiple || game.Rockets != tt.rockets || game.Bombs != 0 {
+			t.Fatalf("step %d: unexpected multiple %d and rockets %d", tt.step, game.Multiple, game.Rockets)
+		}
+	}
+}
+
+func TestReplayBid(t *testing.T) {
+	match := recordedMatch()
+	match.Properties = map[string]bool{consts.RoomPropsBid: true}
+	match.Events = append(match.Events[:1:1],
+		database.MatchEvent{Type: database.MatchEventBid, Player: 1, Base: 2},
+		database.MatchEvent{Type: database.MatchEventBid, Player: 2},
+		database.MatchEvent{Type: database.MatchEventLandlord, Player: 1, Pokers: poker.GetPokers(10)},
+	)
+	for step, base := range []int{consts.BaseScore, 2, 2, 2} {
+		if game := replayTo(match, step); game.Base != base || game.Multiple != 1 {
+			t.Fatalf("step %d: expected base %d, got %d and multiple %d", step, base, game.Base, game.Multiple)
+		}
+	}
+	if view := viewReplay(match, 2); !strings.Contains(view, "maki don't bid") || !strings.Contains(view, "Base: 2, multiple: 1") {
+		t.Fatalf("unexpected view:\n%s", view)
+	}
+}
+
+func TestRemovePokers(t *testing.T) {
+	hand := poker.GetPokers(3, 3, 5, 7)
+	hand[3].Oaa = true
+	played := poker.GetPokers(3, 5, 5)
+	played[2].Oaa = true
+	if rest := removePokers(hand, played); fmt.Sprint(keysOf(rest)) != "[3]" {
+		t.Fatalf("expected a 3 to be left, got %v", keysOf(rest))
+	}
+	if len(hand) != 4 {
+		t.Fatal("expected the hand not to be modified")
+	}
+}
+
+func TestViewReplay(t *testing.T) {
+	match := recordedMatch()
+	view := viewReplay(match, 5)
+	for _, line := range []string{"nico played", "Base: 1, multiple: 4, bombs: 0, rockets: 1", "nico                landlord  10 4"} {
+		if !strings.Contains(view, line) {
+			t.Fatalf("expected %q in the view:\n%s", line, view)
+		}
+	}
+	if view = viewReplay(match, 6); !strings.Contains(view, "nico: +8") || !strings.Contains(view, "maki: -4") {
+		t.Fatalf("expected the scores in the settlement:\n%s", view)
+	}
+}
@@ -0,0 +1,402 @@
+package runfast
+
+import (
+	"bytes"
+	"fmt"
+	constx "github.com/ratel-online/core/consts"
+	"github.com/ratel-online/core/log"
+	modelx "github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/util/poker"
+	"github.com/ratel-online/server/config"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/metrics"
+	"github.com/ratel-online/server/render"
+	"github.com/ratel-online/server/robot"
+	"github.com/ratel-online/server/rule"
+	gamex "github.com/ratel-online/server/state/game"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RunFast is the state of 跑得快: no robbing, the holder of ♠3 plays first, everybody plays for itself
+// and has to beat the last play whenever it can.
+type RunFast struct{}
+
+var (
+	statePlay    = 1
+	stateWaiting = 2
+)
+
+func (g *RunFast) Next(player *database.Player) (consts.StateID, error) {
+	room := database.GetRoom(player.RoomID)
+	if room == nil {
+		return 0, player.WriteError(consts.ErrorsExist)
+	}
+	game := room.Game
+	player.SetAuto(false)
+	buf := bytes.Buffer{}
+	buf.WriteString("Game starting!\n")
+	if first := database.GetPlayer(game.FirstPlayer); first != nil {
+		buf.WriteString(fmt.Sprintf("%s holds ♠3 and plays first\n", first.Name))
+	}
+	buf.WriteString(fmt.Sprintf("Your pokers: %s\n", game.Pokers[player.ID].String()))
+	_ = render.Send(player, render.NewHandEvent(game.Pokers[player.ID], buf.String()))
+	for {
+		if room.State == consts.RoomStateWaiting {
+			return consts.StateWaiting, nil
+		}
+		state := game.Wait(player.ID)
+		switch state {
+		case statePlay:
+			err := handlePlay(player, game)
+			if err != nil {
+				log.Error(err)
+				return 0, err
+			}
+		case stateWaiting:
+			return consts.StateWaiting, nil
+		default:
+			return 0, consts.ErrorsChanClosed
+		}
+	}
+}
+
+func (*RunFast) Exit(player *database.Player) consts.StateID {
+	return consts.StateHome
+}
+
+func handlePlay(player *database.Player, game *database.Game) error {
+	master := player.ID == game.LastPlayer || game.LastPlayer == 0
+	game.Turn = player.ID
+	render.Publish(player.RoomID, render.NewTurnEvent(player, game.PlayTimeOut[player.ID], fmt.Sprintf("%s turn to play\n", player.Name)))
+	return playing(player, game, master)
+}
+
+func playing(player *database.Player, game *database.Game, master bool) error {
+	timeout := game.PlayTimeOut[player.ID]
+	attempts := 0
+	for {
+		attempts++
+		buf := bytes.Buffer{}
+		buf.WriteString("\n")
+		if !master && len(game.LastPokers) > 0 {
+			buf.WriteString(fmt.Sprintf("Last player: %s, played: %s\n", database.GetPlayer(game.LastPlayer).Name, game.LastPokers.String()))
+		}
+		buf.WriteString(fmt.Sprintf("Timeout: %ds, multiple: %d, pokers: %s\n", int(timeout.Seconds()), game.Multiple, game.Pokers[player.ID].String()))
+		_ = render.Send(player, render.NewHandEvent(game.Pokers[player.ID], buf.String()))
+		before := time.Now().Unix()
+		pokers := game.Pokers[player.ID]
+		ans, err := askForPlay(player, game, master, timeout, attempts)
+		if database.GetRoom(player.RoomID) == nil {
+			// 房间在等待出牌时被解散
+			return consts.ErrorsChanClosed
+		}
+		if err != nil {
+			// 超时后打出最小的单张，跟牌时出能管上的最小牌型，管不上才过
+			if master {
+				ans = poker.GetAlias(pokers[0].Key)
+			} else {
+				ans = robot.Greedy.Play(game, player.ID, false)
+			}
+		} else {
+			timeout -= time.Second * time.Duration(time.Now().Unix()-before)
+		}
+		ans = strings.ToLower(ans)
+		if ans == "" {
+			_ = player.WriteString(fmt.Sprintf("%s\n", consts.ErrorsPokersFacesInvalid.Error()))
+			continue
+		} else if ans == "ls" || ans == "v" {
+			viewGame(game, player)
+			continue
+		} else if room := database.GetRoom(player.RoomID); room != nil && render.Query(player, ans, room.Type) {
+			continue
+		} else if ans == "p" || ans == "pass" {
+			if err := passError(game, player.ID, master); err != nil {
+				_ = player.WriteError(err)
+				continue
+			}
+			nextPlayer := database.GetPlayer(game.NextPlayer(player.ID))
+			render.Publish(player.RoomID, render.NewPassEvent(player, game.LastFaces, fmt.Sprintf("%s passed, next %s\n", player.Name, nextPlayer.Name)))
+			if !game.Notify(nextPlayer.ID, statePlay) {
+				return consts.ErrorsChanClosed
+			}
+			return nil
+		}
+		normalPokers := map[int]modelx.Pokers{}
+		for _, v := range pokers {
+			normalPokers[v.Key] = append(normalPokers[v.Key], v)
+		}
+		sells := make(modelx.Pokers, 0)
+		invalid := false
+		for _, alias := range ans {
+			key := poker.GetKey(string(alias))
+			if key == 0 || len(normalPokers[key]) == 0 {
+				invalid = true
+				break
+			}
+			sells = append(sells, normalPokers[key][len(normalPokers[key])-1])
+			normalPokers[key] = normalPokers[key][:len(normalPokers[key])-1]
+		}
+		var facesArr []modelx.Faces
+		if !invalid {
+			facesArr = poker.ParseFaces(sells, game.Rules)
+		}
+		if len(facesArr) == 0 {
+			render.Chat(player, ans)
+			continue
+		}
+		lastFaces := &facesArr[0]
+		if !master && game.LastFaces != nil {
+			access := false
+			for _, faces := range facesArr {
+				if faces.Compare(*game.LastFaces) {
+					access = true
+					lastFaces = &faces
+					break
+				}
+			}
+			if !access {
+				_ = player.WriteString(fmt.Sprintf("%s\n", consts.ErrorsPokersFacesInvalid.Error()))
+				continue
+			}
+		}
+		for _, p := range sells {
+			game.Mnemonic[p.Key]--
+		}
+		pokers = make(modelx.Pokers, 0)
+		for _, curr := range normalPokers {
+			pokers = append(pokers, curr...)
+		}
+		pokers.SortByValue()
+		game.Pokers[player.ID] = pokers
+		game.LastPlayer = player.ID
+		game.LastFaces = lastFaces
+		game.LastPokers = sells
+		game.Discards = append(game.Discards, sells...)
+		game.Plays[player.ID]++
+		if len(pokers) == 0 {
+			render.Publish(player.RoomID, render.NewPlayEvent(player, sells, lastFaces, fmt.Sprintf("%s played %s, won the game! \n", player.Name, sells.String())))
+			countBomb(player, game, *lastFaces)
+			var matchId int64
+			room := database.GetRoom(player.RoomID)
+			if room != nil {
+				matchId = settle(room, game, player.ID).ID
+				room.Lock()
+				room.Game = nil
+				room.State = consts.RoomStateWaiting
+				room.Unlock()
+			}
+			render.Publish(player.RoomID, render.NewGameOverEvent(player, matchId, fmt.Sprintf("Game over, replay id: %d\n", matchId)))
+			for _, playerId := range game.Players {
+				if !game.Notify(playerId, stateWaiting) {
+					return consts.ErrorsChanClosed
+				}
+			}
+			return nil
+		}
+		nextPlayer := database.GetPlayer(game.NextPlayer(player.ID))
+		render.Publish(player.RoomID, render.NewPlayEvent(player, sells, lastFaces, fmt.Sprintf("%s played %s, next %s\n", player.Name, sells.String(), nextPlayer.Name)))
+		countBomb(player, game, *lastFaces)
+		if len(pokers) == 1 {
+			// 报单
+			database.Broadcast(player.RoomID, fmt.Sprintf("Warning! %s has only one poker left\n", player.Name))
+		}
+		if !game.Notify(nextPlayer.ID, statePlay) {
+			return consts.ErrorsChanClosed
+		}
+		return nil
+	}
+}
+
+// askForPlay lets robots and the autopilot answer, an answer of them which got rejected,
+// e.g. a pass while they could beat, falls back to the timeout behavior.
+func askForPlay(player *database.Player, game *database.Game, master bool, timeout time.Duration, attempts int) (string, error) {
+	if player.Auto() {
+		if attempts > 1 {
+			return "", consts.ErrorsTimeout
+		}
+		time.Sleep(consts.AutopilotDelay)
+		return gamex.StrategyOf(player).Play(game, player.ID, master), nil
+	}
+	ans, err := player.AskForString(timeout)
+	gamex.CountTimeouts(player, "play", err)
+	return ans, err
+}
+
+// countBomb doubles the multiple for every bomb.
+func countBomb(player *database.Player, game *database.Game, faces modelx.Faces) {
+	if faces.Type != constx.FacesBomb {
+		return
+	}
+	game.Multiple *= 2
+	game.Bombs++
+	database.Broadcast(player.RoomID, fmt.Sprintf("%s played a bomb, multiple x2, current multiple: %d\n", player.Name, game.Multiple))
+}
+
+// Resume resends the hand, the last play and the current turn to a reconnected player.
+func Resume(player *database.Player) error {
+	room := database.GetRoom(player.RoomID)
+	if room == nil || room.Game == nil {
+		return consts.ErrorsExist
+	}
+	game := room.Game
+	buf := bytes.Buffer{}
+	buf.WriteString("Reconnected to the game!\n")
+	buf.WriteString(fmt.Sprintf("Your pokers: %s\n", game.Pokers[player.ID].String()))
+	if game.LastPlayer != 0 && len(game.LastPokers) > 0 {
+		buf.WriteString(fmt.Sprintf("Last player: %s, played: %s\n", database.GetPlayer(game.LastPlayer).Name, game.LastPokers.String()))
+	}
+	if turn := database.GetPlayer(game.Turn); turn != nil {
+		buf.WriteString(fmt.Sprintf("Now it's %s's turn\n", turn.Name))
+	}
+	return render.Send(player, render.NewHandEvent(game.Pokers[player.ID], buf.String()))
+}
+
+// passError returns why the player can't pass: the master has to play and anyone who can beat the last play has to beat it.
+func passError(game *database.Game, playerId int64, master bool) error {
+	if master {
+		return consts.ErrorsHaveToPlay
+	}
+	if robot.CanBeat(game, playerId) {
+		return consts.ErrorsHaveToBeat
+	}
+	return nil
+}
+
+// 花色，核心库的牌没有花色，跑得快要靠它认出♠3
+const (
+	spade = iota
+	heart
+	club
+	diamond
+)
+
+// card is a poker of the run fast deck along with its suit.
+type card struct {
+	modelx.Poker
+	suit int
+}
+
+// deck is the 48 pokers of run fast: no jokers, only the ♠2 of the 2s and no ♠A.
+func deck() []card {
+	cards := make([]card, 0, 48)
+	for _, key := range []int{3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 1, 2} {
+		for _, suit := range []int{spade, heart, club, diamond} {
+			if key == 2 && suit != spade || key == 1 && suit == spade {
+				continue
+			}
+			p := poker.GetPokers(key)[0]
+			p.Val = rule.RunFastRules.Value(key)
+			cards = append(cards, card{Poker: p, suit: suit})
+		}
+	}
+	return cards
+}
+
+// dealHands splits the cards in the order into hands of the same size, the cards left over are put aside.
+// It returns the holder of ♠3, or 0 if it was put aside.
+func dealHands(players []int64, cards []card, order []int) (map[int64]modelx.Pokers, int64) {
+	size := len(cards) / len(players)
+	hands := map[int64]modelx.Pokers{}
+	first := int64(0)
+	for i, id := range players {
+		hand := make(modelx.Pokers, 0, size)
+		for _, idx := range order[i*size : (i+1)*size] {
+			if cards[idx].Key == 3 && cards[idx].suit == spade {
+				first = id
+			}
+			hand = append(hand, cards[idx].Poker)
+		}
+		hand.SortByValue()
+		hands[id] = hand
+	}
+	return hands, first
+}
+
+// InitGame deals the 48 pokers, 16 each for 3 players and 12 for 4. Nobody robs, the holder of ♠3 plays first.
+func InitGame(room *database.Room) (*database.Game, error) {
+	players := make([]int64, 0)
+	for playerId := range database.RoomPlayers(room.ID) {
+		players = append(players, playerId)
+	}
+	if len(players) < 2 {
+		return nil, consts.ErrorsGamePlayersInvalid
+	}
+	cards := deck()
+	rand.Seed(time.Now().UnixNano())
+	hands, first := dealHands(players, cards, rand.Perm(len(cards)))
+	if first == 0 {
+		first = players[rand.Intn(len(players))]
+	}
+	states := map[int64]chan int{}
+	groups := map[int64]int{}
+	playTimeout := map[int64]time.Duration{}
+	for i, id := range players {
+		states[id] = make(chan int, 1)
+		groups[id] = i
+		playTimeout[id] = config.Get().PlayTimeoutOf(room.Type)
+	}
+	mnemonic := map[int]int{}
+	for _, c := range cards {
+		mnemonic[c.Key]++
+	}
+	states[first] <- statePlay
+	g := &database.Game{
+		Type:        room.Type,
+		States:      states,
+		Players:     players,
+		Groups:      groups,
+		Pokers:      hands,
+		Additional:  modelx.Pokers{},
+		Base:        consts.BaseScore,
+		Multiple:    1,
+		Mnemonic:    mnemonic,
+		Decks:       1,
+		Properties:  room.GetProperties(),
+		PlayTimes:   map[int64]int{},
+		PlayTimeOut: playTimeout,
+		Plays:       map[int64]int{},
+		FirstPlayer: first,
+		Rules:       rule.RunFastRules,
+		Discards:    modelx.Pokers{},
+		StartTime:   time.Now(),
+	}
+	gamex.Observe(room.ID, g)
+	render.Publish(room.ID, render.NewDealEvent(g.Hands(), modelx.Pokers{}, nil, nil, ""))
+	metrics.GamesStarted.Inc(consts.GameTypes[room.Type])
+	return g, nil
+}
+
+func viewGame(game *database.Game, currPlayer *database.Player) {
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("%-20s%-10s\n", "Name", "Pokers"))
+	for _, id := range game.Players {
+		player := database.GetPlayer(id)
+		flag := ""
+		if id == currPlayer.ID {
+			flag = "*"
+		}
+		buf.WriteString(fmt.Sprintf("%-20s%-10d\n", player.Name+flag, len(game.Pokers[id])))
+	}
+	buf.WriteString(fmt.Sprintf("Multiple: %d, bombs: %d\n", game.Multiple, game.Bombs))
+	currKeys := map[int]int{}
+	for _, currPoker := range game.Pokers[currPlayer.ID] {
+		currKeys[currPoker.Key]++
+	}
+	buf.WriteString("Pokers  : ")
+	for _, i := range consts.MnemonicSorted[2:] {
+		buf.WriteString(poker.GetDesc(i) + "  ")
+	}
+	buf.WriteString("\nSurplus : ")
+	for _, i := range consts.MnemonicSorted[2:] {
+		buf.WriteString(strconv.Itoa(game.Mnemonic[i]-currKeys[i]) + "  ")
+		if i == 10 {
+			buf.WriteString(" ")
+		}
+	}
+	buf.WriteString("\n")
+	_ = currPlayer.WriteString(buf.String())
+}
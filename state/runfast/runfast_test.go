@@ -0,0 +1,143 @@
+package runfast
+
+import (
+	"github.com/ratel-online/core/model"
+	"github.com/ratel-online/core/util/poker"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/rule"
+	"math/rand"
+	"testing"
+)
+
+func TestDeck(t *testing.T) {
+	cards := deck()
+	counts, suits := map[int]int{}, map[card]int{}
+	for _, c := range cards {
+		counts[c.Key]++
+		suits[card{Poker: model.Poker{Key: c.Key}, suit: c.suit}]++
+	}
+	if len(cards) != 48 || counts[2] != 1 || counts[1] != 3 || counts[14] != 0 || counts[15] != 0 {
+		t.Fatalf("unexpected deck %v", counts)
+	}
+	for key := 3; key <= 13; key++ {
+		if counts[key] != 4 {
+			t.Fatalf("expected 4 pokers of %d, got %d", key, counts[key])
+		}
+	}
+	tests := []struct {
+		key   int
+		suit  int
+		count int
+	}{
+		{key: 3, suit: spade, count: 1},
+		{key: 2, suit: spade, count: 1},
+		{key: 2, suit: heart, count: 0},
+		{key: 1, suit: spade, count: 0},
+		{key: 1, suit: diamond, count: 1},
+	}
+	for _, tt := range tests {
+		if n := suits[card{Poker: model.Poker{Key: tt.key}, suit: tt.suit}]; n != tt.count {
+			t.Fatalf("expected %d of key %d suit %d, got %d", tt.count, tt.key, tt.suit, n)
+		}
+	}
+}
+
+func TestDeal(t *testing.T) {
+	tests := []struct {
+		players int
+		size    int
+	}{
+		{players: 3, size: 16},
+		{players: 4, size: 12},
+	}
+	for _, tt := range tests {
+		players := make([]int64, 0, tt.players)
+		for i := 1; i <= tt.players; i++ {
+			players = append(players, int64(i))
+		}
+		cards := deck()
+		order := rand.Perm(len(cards))
+		hands, first := dealHands(players, cards, order)
+		for _, id := range players {
+			if len(hands[id]) != tt.size {
+				t.Fatalf("%d players: expected %d pokers each, got %d", tt.players, tt.size, len(hands[id]))
+			}
+		}
+		// ♠3按花色认出，与发牌顺序无关
+		holder := int64(0)
+		for i, idx := range order {
+			if cards[idx].Key == 3 && cards[idx].suit == spade {
+				holder = players[i/tt.size]
+			}
+		}
+		if first != holder {
+			t.Fatalf("%d players: expected %d to hold ♠3, got %d", tt.players, holder, first)
+		}
+	}
+}
+
+func TestPassError(t *testing.T) {
+	last := pokersOf(5)
+	tests := []struct {
+		hand   model.Pokers
+		master bool
+		err    error
+	}{
+		{hand: pokersOf(3), master: true, err: consts.ErrorsHaveToPlay},
+		{hand: pokersOf(3, 6), err: consts.ErrorsHaveToBeat},
+		{hand: pokersOf(3, 4), err: nil},
+	}
+	for _, tt := range tests {
+		game := &database.Game{
+			Pokers:     map[int64]model.Pokers{1: tt.hand},
+			Rules:      rule.RunFastRules,
+			Properties: map[string]bool{},
+			LastFaces:  &poker.ParseFaces(last, rule.RunFastRules)[0],
+		}
+		if err := passError(game, 1, tt.master); err != tt.err {
+			t.Fatalf("hand %v master %v: expected %v, got %v", tt.hand, tt.master, tt.err, err)
+		}
+	}
+}
+
+func TestSettle(t *testing.T) {
+	defer database.SetStore(database.GetStore())
+	database.SetStore(database.NewMemoryStore())
+
+	room := database.CreateRoom(1, "", 3)
+	room.Type = consts.GameTypeRunFast
+	players := make([]int64, 0)
+	for i := 0; i < 3; i++ {
+		robot, err := database.AddRobot(room.ID, consts.RobotLevelEasy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		players = append(players, robot.ID)
+	}
+	defer database.DissolveRoom(room.ID)
+	game := &database.Game{
+		Players:    players,
+		Pokers:     map[int64]model.Pokers{players[0]: {}, players[1]: pokersOf(3, 4, 5), players[2]: pokersOf(3, 4, 5, 6, 7)},
+		Base:       1,
+		Multiple:   2,
+		Properties: map[string]bool{},
+	}
+	match := settle(room, game, players[0])
+	expected := map[int64]int64{players[0]: 16, players[1]: -6, players[2]: -10}
+	for _, p := range match.Players {
+		if p.Score != expected[p.ID] || p.Winner != (p.ID == players[0]) {
+			t.Fatalf("unexpected settlement %+v", match.Players)
+		}
+	}
+}
+
+func pokersOf(keys ...int) model.Pokers {
+	pokers := make(model.Pokers, 0, len(keys))
+	for _, key := range keys {
+		p := poker.GetPokers(key)[0]
+		p.Val = rule.RunFastRules.Value(key)
+		pokers = append(pokers, p)
+	}
+	return pokers
+}
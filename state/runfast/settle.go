@@ -0,0 +1,80 @@
+package runfast
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ratel-online/core/log"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/metrics"
+	"github.com/ratel-online/server/rating"
+	"github.com/ratel-online/server/render"
+	"time"
+)
+
+// settle computes the scores of a finished hand, every loser pays the base score times the multiple
+// for each poker left in its hand to the winner.
+func settle(room *database.Room, game *database.Game, winner int64) *database.Match {
+	unit := int64(game.Base * game.Multiple)
+	scores := map[int64]int64{}
+	for _, id := range game.Players {
+		if id == winner {
+			continue
+		}
+		score := unit * int64(len(game.Pokers[id]))
+		scores[winner] += score
+		scores[id] -= score
+	}
+	seats := make([]rating.Seat, 0, len(game.Players))
+	for _, id := range game.Players {
+		seats = append(seats, rating.Seat{
+			ID:     id,
+			Rating: database.GetRating(id, room.Type),
+			Winner: id == winner,
+			Robot:  database.GetPlayer(id).IsRobot(),
+		})
+	}
+	deltas, rated := rating.Update(seats), rating.Rated(seats)
+	metrics.GamesFinished.Inc(consts.GameTypes[room.Type])
+	metrics.HandDuration.Since(game.StartTime, consts.GameTypes[room.Type])
+
+	match := &database.Match{
+		Version:    database.MatchVersion,
+		RoomID:     room.ID,
+		Type:       room.Type,
+		Multiple:   game.Multiple,
+		Properties: game.Properties,
+		StartTime:  game.StartTime,
+		EndTime:    time.Now(),
+	}
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("Settlement, base: %d, multiple: %d, bombs: %d\n", game.Base, game.Multiple, game.Bombs))
+	buf.WriteString(fmt.Sprintf("%-20s%-10s%-10s%-10s%-10s\n", "Name", "Left", "Score", "Total", "Rating"))
+	for i, id := range game.Players {
+		player := database.GetPlayer(id)
+		player.Score += scores[id]
+		if err := database.SavePlayer(player); err != nil {
+			log.Error(err)
+		}
+		newRating := seats[i].Rating + deltas[id]
+		if rated {
+			if err := database.SetRating(id, room.Type, newRating); err != nil {
+				log.Error(err)
+			}
+		}
+		match.Players = append(match.Players, database.MatchPlayer{
+			ID:     id,
+			Name:   player.Name,
+			Winner: id == winner,
+			Score:  scores[id],
+			Rating: newRating,
+		})
+		buf.WriteString(fmt.Sprintf("%-20s%-10d%-10s%-10d%-10s\n", player.Name, len(game.Pokers[id]), fmt.Sprintf("%+d", scores[id]), player.Score, fmt.Sprintf("%d(%+d)", newRating, deltas[id])))
+	}
+	render.Publish(room.ID, render.NewSettleEvent(match, game.Base, buf.String()))
+	match.Events = game.Events
+	if err := database.GetStore().SaveMatch(match); err != nil {
+		log.Error(err)
+	}
+	return match
+}
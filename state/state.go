@@ -1,11 +1,13 @@
 package state
 
 import (
+	"fmt"
 	"github.com/ratel-online/core/log"
 	"github.com/ratel-online/core/util/async"
 	"github.com/ratel-online/server/consts"
 	"github.com/ratel-online/server/database"
 	"github.com/ratel-online/server/state/game"
+	"github.com/ratel-online/server/state/runfast"
 	"strings"
 )
 
@@ -18,6 +20,10 @@ func init() {
 	register(consts.StateNew, &new{})
 	register(consts.StateWaiting, &waiting{})
 	register(consts.StateGame, &game.Game{})
+	register(consts.StateMatch, &match{})
+	register(consts.StateReplay, &replay{})
+	register(consts.StateWatch, &watch{})
+	register(consts.StateRunFast, &runfast.RunFast{})
 }
 
 func register(id consts.StateID, state State) {
@@ -31,10 +37,70 @@ type State interface {
 
 func Run(player *database.Player) {
 	player.State(consts.StateWelcome)
+	run(player)
+}
+
+// Resume brings a reconnected player back to the seat of its running game.
+// The state machine is only restarted if it has broken up in the meantime.
+func Resume(player *database.Player) {
+	gameState := gameStateOf(database.GetRoom(player.RoomID))
+	resume := game.Resume
+	if gameState == consts.StateRunFast {
+		resume = runfast.Resume
+	}
+	err := resume(player)
+	if err != nil {
+		log.Error(err)
+	}
+	// 旧的状态机还在运行时不再启动第二个
+	if !player.StartRunning() {
+		return
+	}
+	player.State(gameState)
+	loop(player)
+}
+
+// RunRobot drives a robot seat through the game, robots have no connection to wait
+// for input in other states, so the machine breaks up as soon as the game is over.
+func RunRobot(player *database.Player) {
+	defer func() {
+		if err := recover(); err != nil {
+			async.PrintStackTrace(err)
+			// 机器人的座位没人接手，作废这局，免得其他玩家一直等下去
+			database.VoidRoom(player.RoomID, fmt.Sprintf("%s crashed, the game is void\n", player.Name))
+		}
+	}()
+	state := states[gameStateOf(database.GetRoom(player.RoomID))]
+	for {
+		stateId, err := state.Next(player)
+		if err != nil || stateId > 0 {
+			break
+		}
+	}
+}
+
+// gameStateOf returns the state playing the game of the room, run fast has its own.
+func gameStateOf(room *database.Room) consts.StateID {
+	if room != nil && room.Type == consts.GameTypeRunFast {
+		return consts.StateRunFast
+	}
+	return consts.StateGame
+}
+
+func run(player *database.Player) {
+	if !player.StartRunning() {
+		return
+	}
+	loop(player)
+}
+
+// loop drives the state machine of the player, the caller has to mark it running.
+func loop(player *database.Player) {
 	defer func() {
 		if err := recover(); err != nil {
 			async.PrintStackTrace(err)
 		}
+		player.StopRunning()
 		log.Infof("player %s state machine break up.\n", player)
 	}()
 	for {
@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"fmt"
 	"github.com/awesome-cap/hashmap"
+	"github.com/ratel-online/core/util/poker"
+	"github.com/ratel-online/server/config"
 	"github.com/ratel-online/server/consts"
 	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/render"
 	"github.com/ratel-online/server/rule"
 	"github.com/ratel-online/server/state/game"
+	"github.com/ratel-online/server/state/runfast"
 	"strconv"
 	"strings"
 	"time"
@@ -20,19 +24,13 @@ func (s *waiting) Next(player *database.Player) (consts.StateID, error) {
 	if room == nil {
 		return 0, consts.ErrorsExist
 	}
-	if room.Type == consts.GameTypeLaiZi {
-		room.SetProperty(consts.RoomPropsLaiZi, true)
-	} else if room.Type == consts.GameTypeSkill {
-		room.SetProperty(consts.RoomPropsLaiZi, true)
-		room.SetProperty(consts.RoomPropsDotShuffle, true)
-		room.SetProperty(consts.RoomPropsSkill, true)
-	}
+	applyGameType(room)
 	access, err := waitingForStart(player, room)
 	if err != nil {
 		return 0, err
 	}
 	if access {
-		return consts.StateGame, nil
+		return gameStateOf(room), nil
 	}
 	return s.Exit(player), nil
 }
@@ -51,6 +49,17 @@ func (*waiting) Exit(player *database.Player) consts.StateID {
 	return consts.StateHome
 }
 
+// applyGameType turns on the room properties required by the game type.
+func applyGameType(room *database.Room) {
+	if room.Type == consts.GameTypeLaiZi {
+		room.SetProperty(consts.RoomPropsLaiZi, true)
+	} else if room.Type == consts.GameTypeSkill {
+		room.SetProperty(consts.RoomPropsLaiZi, true)
+		room.SetProperty(consts.RoomPropsDotShuffle, true)
+		room.SetProperty(consts.RoomPropsSkill, true)
+	}
+}
+
 func waitingForStart(player *database.Player, room *database.Room) (bool, error) {
 	access := false
 	player.StartTransaction()
@@ -64,10 +73,19 @@ func waitingForStart(player *database.Player, room *database.Room) (bool, error)
 			access = true
 			break
 		}
+		if database.GetRoom(room.ID) == nil {
+			break
+		}
 		signal = strings.ToLower(signal)
 		if signal == "ls" || signal == "v" {
 			viewRoomPlayers(room, player)
-		} else if (signal == "start" || signal == "s") && room.Creator == player.ID && room.Players > 1 {
+		} else if render.Query(player, signal, room.Type) {
+			continue
+		} else if (signal == "start" || signal == "s") && room.Creator == player.ID && room.Players >= config.Get().MinPlayersOf(room.Type) {
+			if database.ShuttingDown() {
+				_ = player.WriteError(consts.ErrorsServerShuttingDown)
+				continue
+			}
 			access = true
 			room.Lock()
 			room.Game, err = initGame(room)
@@ -77,7 +95,20 @@ func waitingForStart(player *database.Player, room *database.Room) (bool, error)
 			}
 			room.State = consts.RoomStateRunning
 			room.Unlock()
+			for _, robot := range database.GetRobots(room.ID) {
+				go RunRobot(robot)
+			}
 			break
+		} else if strings.HasPrefix(signal, "add robot") && room.Creator == player.ID {
+			level := consts.RobotLevelEasy
+			if tags := strings.Fields(signal); len(tags) == 3 {
+				if _, ok := consts.RobotLevels[tags[2]]; !ok {
+					_ = player.WriteError(consts.ErrorsInputInvalid)
+					continue
+				}
+				level = consts.RobotLevels[tags[2]]
+			}
+			addRobot(player, room, level)
 		} else if strings.HasPrefix(signal, "set ") && room.Creator == player.ID {
 			tags := strings.Split(signal, " ")
 			if len(tags) == 3 {
@@ -85,44 +116,85 @@ func waitingForStart(player *database.Player, room *database.Room) (bool, error)
 				case consts.RoomPropsPassword:
 					pwd := strings.TrimSpace(tags[2])
 
-					// 不允许10位以上的密码，防止恶意输入超长文本占满服务器资源
-					if len(pwd) > 10 {
+					// 限制密码长度，防止恶意输入超长文本占满服务器资源
+					if limit := config.Get().PasswordLength; len(pwd) > limit {
 						pwd = ""
 						buf := bytes.Buffer{}
-						buf.WriteString("Your password is too long, must less 10 charts.  \n")
+						buf.WriteString(fmt.Sprintf("Your password is too long, must less %d charts.  \n", limit))
 						_ = player.WriteString(buf.String())
 					}
 
 					room.Password = pwd
 				case consts.RoomPropsPlayerNum:
 					playerNum, err := strconv.Atoi(strings.TrimSpace(tags[2]))
-					if err == nil && playerNum > 1 && playerNum <= consts.MaxPlayers {
+					if err == nil && playerNum >= config.Get().MinPlayersOf(room.Type) && playerNum <= config.Get().MaxPlayersOf(room.Type) {
 						room.MaxPlayer = playerNum
 					}
+				case consts.RoomPropsRobot:
+					robots, err := strconv.Atoi(strings.TrimSpace(tags[2]))
+					if err != nil || robots < 0 {
+						_ = player.WriteError(consts.ErrorsInputInvalid)
+						break
+					}
+					for room.Robots > robots {
+						if !removeRobot(room) {
+							break
+						}
+					}
+					for room.Robots < robots {
+						if !addRobot(player, room, consts.RobotLevelEasy) {
+							break
+						}
+					}
 				default:
 					room.SetProperty(tags[1], tags[2] == "on")
 				}
 				continue
 			}
-			database.BroadcastChat(player, fmt.Sprintf("%s say: %s\n", player.Name, signal))
+			render.Chat(player, signal)
 		} else if len(signal) > 0 {
-			database.BroadcastChat(player, fmt.Sprintf("%s say: %s\n", player.Name, signal))
+			render.Chat(player, signal)
 		}
 	}
 	return access, nil
 }
 
+func addRobot(player *database.Player, room *database.Room, level int) bool {
+	robot, err := database.AddRobot(room.ID, level)
+	if err != nil {
+		_ = player.WriteError(err)
+		return false
+	}
+	database.Broadcast(room.ID, fmt.Sprintf("%s joined room! room current has %d players\n", robot.Name, room.Players))
+	return true
+}
+
+func removeRobot(room *database.Room) bool {
+	robot := database.RemoveRobot(room.ID)
+	if robot == nil {
+		return false
+	}
+	database.Broadcast(room.ID, fmt.Sprintf("%s exited room! room current has %d players\n", robot.Name, room.Players))
+	return true
+}
+
 func viewRoomPlayers(room *database.Room, currPlayer *database.Player) {
 	buf := bytes.Buffer{}
 	buf.WriteString(fmt.Sprintf("Room ID: %d\n", room.ID))
-	buf.WriteString(fmt.Sprintf("%-20s%-10s%-10s\n", "Name", "Score", "Title"))
+	buf.WriteString(fmt.Sprintf("%-20s%-10s%-10s%-10s\n", "Name", "Score", "Rating", "Title"))
 	for playerId := range database.RoomPlayers(room.ID) {
 		title := "player"
 		if playerId == room.Creator {
 			title = "owner"
 		}
 		player := database.GetPlayer(playerId)
-		buf.WriteString(fmt.Sprintf("%-20s%-10d%-10s\n", player.Name, player.Score, title))
+		if player.IsRobot() {
+			title = "robot"
+		}
+		buf.WriteString(fmt.Sprintf("%-20s%-10d%-10d%-10s\n", player.Name, player.Score, database.GetRating(playerId, room.Type), title))
+	}
+	if room.Spectators > 0 {
+		buf.WriteString(fmt.Sprintf("Spectators: %d\n", room.Spectators))
 	}
 	buf.WriteString("Properties: ")
 	room.Properties.Foreach(func(e *hashmap.Entry) {
@@ -135,9 +207,14 @@ func viewRoomPlayers(room *database.Room, currPlayer *database.Player) {
 }
 
 func initGame(room *database.Room) (*database.Game, error) {
-	rules := rule.LandlordRules
+	if room.Type == consts.GameTypeRunFast {
+		return runfast.InitGame(room)
+	}
+	var rules poker.Rules = rule.LandlordRules
 	if room.GetProperty(consts.RoomPropsSkill) {
 		rules = rule.TeamRules
+	} else if len(database.RoomPlayers(room.ID)) == 4 {
+		rules = rule.FourPlayerRules
 	}
 	return game.InitGame(room, rules)
 }
@@ -0,0 +1,75 @@
+package state
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/ratel-online/server/consts"
+	"github.com/ratel-online/server/database"
+	"github.com/ratel-online/server/render"
+	gamex "github.com/ratel-online/server/state/game"
+	"strings"
+	"time"
+)
+
+type watch struct{}
+
+func (s *watch) Next(player *database.Player) (consts.StateID, error) {
+	room := database.GetRoom(player.WatchID)
+	if room == nil {
+		return s.Exit(player), nil
+	}
+	_ = player.WriteString(fmt.Sprintf("Watching room %d, input v to view the table, anything else chats with other spectators, e to exit\n", room.ID))
+	viewWatch(room, player)
+	player.StartTransaction()
+	defer player.StopTransaction()
+	for {
+		signal, err := player.AskForStringWithoutTransaction(time.Second)
+		if err != nil && err != consts.ErrorsTimeout {
+			return 0, err
+		}
+		if database.GetRoom(room.ID) == nil || player.WatchID != room.ID {
+			_ = player.WriteString("Room dissolved\n")
+			return s.Exit(player), nil
+		}
+		signal = strings.TrimSpace(signal)
+		if signal == "" {
+			continue
+		}
+		if isLs(signal) || strings.ToLower(signal) == "v" {
+			viewWatch(room, player)
+		} else if render.Query(player, strings.ToLower(signal), room.Type) {
+			continue
+		} else {
+			render.SpectatorChat(player, signal)
+		}
+	}
+}
+
+func (*watch) Exit(player *database.Player) consts.StateID {
+	database.UnwatchRoom(player.WatchID, player.ID)
+	return consts.StateHome
+}
+
+// viewWatch shows the public view of the table, card counts and the last play, never the hands.
+func viewWatch(room *database.Room, player *database.Player) {
+	// 对局结束或重新开始时房间会换上新的对局
+	room.Lock()
+	game, state, spectators := room.Game, room.State, room.Spectators
+	room.Unlock()
+	if game == nil || state != consts.RoomStateRunning {
+		viewRoomPlayers(room, player)
+		return
+	}
+	buf := bytes.Buffer{}
+	buf.WriteString(fmt.Sprintf("Room ID: %d, spectators: %d\n", room.ID, spectators))
+	buf.WriteString(gamex.Table(game, func(id int64) string {
+		if id == game.Turn {
+			return database.GetPlayer(id).Name + "*"
+		}
+		return database.GetPlayer(id).Name
+	}, false))
+	if game.LastPlayer != 0 && len(game.LastPokers) > 0 {
+		buf.WriteString(fmt.Sprintf("Last player: %s (%s), played: %s\n", database.GetPlayer(game.LastPlayer).Name, game.Team(game.LastPlayer), game.LastPokers.String()))
+	}
+	_ = player.WriteString(buf.String())
+}